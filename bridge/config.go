@@ -17,16 +17,31 @@ type ConfigBridge struct {
 	logger log.Logger
 
 	// Models.
-	GameModel *core.QAbstractListModel `property:"games"`
+	GameModel    *core.QAbstractListModel `property:"games"`
+	GatewayModel *core.QAbstractListModel `property:"gateways"`
+	FlagModel    *core.QAbstractListModel `property:"knownFlags"`
 
 	// Properties.
-	_ string `property:"buildVersion"`
+	_ string   `property:"buildVersion"`
+	_ string   `property:"activeProfile"`
+	_ []string `property:"profileNames"`
+	_ string   `property:"importError"`
+
+	// Signals.
+	_ func(gateway string) `signal:"profileSwitched"`
+	_ func(gateway string) `signal:"configImported"`
 
 	// Slots.
 	_ func()                 `slot:"addGame"`
 	_ func(body string) bool `slot:"upsertGame"`
 	_ func(id string)        `slot:"deleteGame"`
 	_ func() bool            `slot:"persistGameModel"`
+	_ func(body string) bool `slot:"addGateway"`
+	_ func(name string) bool `slot:"deleteGateway"`
+	_ func(name string) bool `slot:"addProfile"`
+	_ func(name string) bool `slot:"switchProfile"`
+	_ func(path string) bool `slot:"exportConfig"`
+	_ func(body string) bool `slot:"importConfig"`
 }
 
 // Connect will connect the QML signals to functions in Go.
@@ -35,6 +50,12 @@ func (c *ConfigBridge) Connect() {
 	c.ConnectAddGame(c.addGame)
 	c.ConnectDeleteGame(c.deleteGame)
 	c.ConnectPersistGameModel(c.persistGameModel)
+	c.ConnectAddGateway(c.addGateway)
+	c.ConnectDeleteGateway(c.deleteGateway)
+	c.ConnectAddProfile(c.addProfile)
+	c.ConnectSwitchProfile(c.switchProfile)
+	c.ConnectExportConfig(c.exportConfig)
+	c.ConnectImportConfig(c.importConfig)
 }
 
 // addGame will add a game to the game model.
@@ -76,16 +97,137 @@ func (c *ConfigBridge) persistGameModel() bool {
 	return true
 }
 
+// addGateway will add a custom gateway.
+func (c *ConfigBridge) addGateway(body string) bool {
+	var request config.AddGatewayRequest
+	if err := json.Unmarshal([]byte(body), &request); err != nil {
+		c.logger.Error(err)
+		return false
+	}
+
+	if err := c.config.AddGateway(request); err != nil {
+		c.logger.Error(err)
+		return false
+	}
+
+	return true
+}
+
+// deleteGateway will delete the custom gateway with the given name.
+func (c *ConfigBridge) deleteGateway(name string) bool {
+	if err := c.config.DeleteGateway(name); err != nil {
+		c.logger.Error(err)
+		return false
+	}
+
+	return true
+}
+
+// addProfile will add a profile without any games.
+func (c *ConfigBridge) addProfile(name string) bool {
+	if err := c.config.AddProfile(name); err != nil {
+		c.logger.Error(err)
+		return false
+	}
+
+	c.updateProfiles()
+
+	return true
+}
+
+// switchProfile will switch to the profile with the given name,
+// the gateway of the profile is sent with the profileSwitched signal.
+func (c *ConfigBridge) switchProfile(name string) bool {
+	if err := c.config.SwitchProfile(name); err != nil {
+		c.logger.Error(err)
+		return false
+	}
+
+	c.updateProfiles()
+
+	conf, err := c.config.Read()
+	if err != nil {
+		c.logger.Error(err)
+		return false
+	}
+
+	c.ProfileSwitched(conf.Gateway)
+
+	return true
+}
+
+// exportConfig will export the launcher setup to the file at the path.
+func (c *ConfigBridge) exportConfig(path string) bool {
+	if err := c.config.Export(path); err != nil {
+		c.logger.Error(err)
+		return false
+	}
+
+	return true
+}
+
+// importConfig will import the launcher setup, the gateway
+// of the setup is sent with the configImported signal.
+func (c *ConfigBridge) importConfig(body string) bool {
+	var request config.ImportRequest
+	if err := json.Unmarshal([]byte(body), &request); err != nil {
+		c.logger.Error(err)
+		return false
+	}
+
+	// The setup is validated before importing, tell the user what's wrong with it.
+	if err := c.config.Import(request); err != nil {
+		c.logger.Error(err)
+		c.SetImportError(err.Error())
+		return false
+	}
+
+	c.SetImportError("")
+
+	conf, err := c.config.Read()
+	if err != nil {
+		c.logger.Error(err)
+		return false
+	}
+
+	c.ConfigImported(conf.Gateway)
+
+	return true
+}
+
+// updateProfiles will set the profiles on the bridge.
+func (c *ConfigBridge) updateProfiles() {
+	conf, err := c.config.Read()
+	if err != nil {
+		c.logger.Error(err)
+		return
+	}
+
+	names, err := c.config.ProfileNames()
+	if err != nil {
+		c.logger.Error(err)
+		return
+	}
+
+	c.SetActiveProfile(conf.ActiveProfile)
+	c.SetProfileNames(names)
+}
+
 // NewConfig returns a new config bridge with all dependencies set up.
-func NewConfig(cs config.Service, gm *config.GameModel, logger log.Logger) *ConfigBridge {
+func NewConfig(cs config.Service, gm *config.GameModel, gwm *config.GatewayModel, fm *config.FlagModel, logger log.Logger) *ConfigBridge {
 	configBridge := NewConfigBridge(nil)
 
 	// Setup dependencies.
 	configBridge.config = cs
 	configBridge.logger = logger
 
-	// Setup model.
+	// Setup models.
 	configBridge.SetGames(gm)
+	configBridge.SetGateways(gwm)
+	configBridge.SetKnownFlags(fm)
+
+	// Setup profiles.
+	configBridge.updateProfiles()
 
 	return configBridge
 }
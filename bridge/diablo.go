@@ -1,6 +1,11 @@
 package bridge
 
 import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
 	"github.com/nokka/slashdiablo-launcher/d2"
 	"github.com/nokka/slashdiablo-launcher/log"
 	"github.com/therecipe/qt/core"
@@ -12,8 +17,18 @@ type DiabloBridge struct {
 	core.QObject
 
 	// Dependencies.
-	d2service d2.Service
-	logger    log.Logger
+	d2service       d2.Service
+	validationModel *d2.ValidationModel
+	instanceModel   *d2.InstanceModel
+	logger          log.Logger
+
+	// Models.
+	ValidationModel *core.QAbstractListModel `property:"validation"`
+	InstanceModel   *core.QAbstractListModel `property:"instances"`
+
+	// Cancels the running patch.
+	cancelPatching context.CancelFunc
+	mux            sync.Mutex
 
 	// Properties.
 	_ bool    `property:"patching"`
@@ -23,33 +38,73 @@ type DiabloBridge struct {
 	_ float32 `property:"patchProgress"`
 	_ string  `property:"status"`
 	_ string  `property:"gateway"`
+	_ bool    `property:"launching"`
+	_ string  `property:"launchStatus"`
+	_ string  `property:"lastCrash"`
 
 	// Slots.
 	_ func()                 `slot:"launchGame"`
 	_ func()                 `slot:"validateVersion"`
 	_ func()                 `slot:"applyPatches"`
+	_ func()                 `slot:"cancelPatch"`
 	_ func(path string) bool `slot:"applyDEP"`
 	_ func(gateway string)   `slot:"updateGateway"`
+	_ func(pid int)          `slot:"stopInstance"`
+	_ func(gameID string)    `slot:"stopGame"`
+	_ func()                 `slot:"stopAll"`
 }
 
 // Connect will connect the QML signals to functions in Go.
 func (b *DiabloBridge) Connect() {
 	b.ConnectLaunchGame(b.launchGame)
 	b.ConnectApplyPatches(b.applyPatches)
+	b.ConnectCancelPatch(b.cancelPatch)
 	b.ConnectValidateVersion(b.validateVersion)
 	b.ConnectApplyDEP(b.applyDEP)
 	b.ConnectUpdateGateway(b.updateGateway)
+	b.ConnectStopInstance(b.stopInstance)
+	b.ConnectStopGame(b.stopGame)
+	b.ConnectStopAll(b.stopAll)
+
+	// Keep the instance model up to date for as long as the app runs.
+	go b.watchInstances()
 }
 
 func (b *DiabloBridge) launchGame() {
+	// Only launch once at a time, otherwise we'd launch too many instances.
+	if b.IsLaunching() {
+		return
+	}
+
+	b.SetLaunching(true)
+
 	// Do the work on another thread not to lock the GUI.
 	go func() {
-		err := b.d2service.Exec()
-		if err != nil {
-			b.logger.Error(err)
+		defer b.SetLaunching(false)
+
+		for state := range b.d2service.Exec(context.Background()) {
+			// Instances are named by their profile, if they have one.
+			name := fmt.Sprintf("instance %d of %d", state.Instance, state.Instances)
+			if state.Label != "" {
+				name = state.Label
+			}
+
+			switch state.Status {
+			case d2.LaunchStarting:
+				b.SetLaunchStatus(fmt.Sprintf("Starting %s", name))
+			case d2.LaunchWaiting:
+				b.SetLaunchStatus(fmt.Sprintf("Waiting for %s", name))
+			case d2.LaunchReady:
+				b.SetLaunchStatus(fmt.Sprintf("Launched %s", name))
+			case d2.LaunchFailed:
+				b.logger.Error(state.Error)
+				b.SetLaunchStatus("Couldn't launch Diablo II")
+				return
+			}
 		}
-	}()
 
+		b.SetLaunchStatus("")
+	}()
 }
 
 func (b *DiabloBridge) applyPatches() {
@@ -57,26 +112,59 @@ func (b *DiabloBridge) applyPatches() {
 	b.SetPatching(true)
 	b.SetValidVersion(false)
 
+	// The context allows the patch to be cancelled from the GUI.
+	ctx, cancel := context.WithCancel(context.Background())
+
+	b.mux.Lock()
+	b.cancelPatching = cancel
+	b.mux.Unlock()
+
 	// Run this on a separate thread so we don't block the UI.
 	go func() {
+		defer cancel()
+
 		done := make(chan bool, 1)
 
 		// Let the patcher run, it returns a channel
 		// where we get the progress from, and another channel with errors.
-		progress, state := b.d2service.Patch(done)
+		progress, state := b.d2service.Patch(ctx, done)
 
 		for {
 			select {
-			case percentage := <-progress:
+			case percentage, ok := <-progress:
+				// Stop listening once the patcher has stopped.
+				if !ok {
+					progress = nil
+					continue
+				}
+
 				b.SetPatchProgress(percentage)
-			case current := <-state:
+			case current, ok := <-state:
+				// The patcher stopped, done is sent before it stops so it was cancelled unless done is waiting.
+				if !ok {
+					select {
+					case <-done:
+						b.SetPatching(false)
+						b.validateVersion()
+						return
+					default:
+					}
+
+					b.SetStatus("Patching was cancelled")
+					b.SetPatching(false)
+					b.validateVersion()
+					return
+				}
+
 				if current.Error != nil {
 					// Log the error to persistent logging store.
 					b.logger.Error(current.Error)
 
-					// Update bridge state.
+					// Update bridge state, the message describes the error.
+					b.SetStatus(current.Message)
 					b.SetErrored(true)
 					b.SetPatching(false)
+					return
 				}
 
 				if current.Message != "" {
@@ -91,6 +179,15 @@ func (b *DiabloBridge) applyPatches() {
 	}()
 }
 
+func (b *DiabloBridge) cancelPatch() {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+
+	if b.cancelPatching != nil {
+		b.cancelPatching()
+	}
+}
+
 func (b *DiabloBridge) validateVersion() {
 	// Update GUI and reset errors.
 	b.SetValidatingVersion(true)
@@ -98,17 +195,131 @@ func (b *DiabloBridge) validateVersion() {
 
 	// Do the work on another thread not to lock the GUI.
 	go func() {
-		valid, err := b.d2service.ValidateGameVersions()
+		report, err := b.d2service.ValidateGameVersions(context.Background())
 		if err != nil {
 			b.logger.Error(err)
 			b.SetErrored(true)
+			b.SetValidVersion(false)
+			b.SetValidatingVersion(false)
+			return
 		}
 
-		b.SetValidVersion(valid)
+		// Update the model with the report of each game.
+		b.validationModel.Clear()
+		for _, game := range report.Games {
+			b.validationModel.AddItem(newValidationItem(game))
+		}
+
+		b.SetValidVersion(report.UpToDate())
 		b.SetValidatingVersion(false)
 	}()
 }
 
+// newValidationItem will create a new QObject item from the game report that we can pass to the model.
+func newValidationItem(game d2.GameReport) *d2.ValidationItem {
+	i := d2.NewValidationItem(nil)
+	i.GameID = game.GameID
+	i.Location = game.Location
+	i.Version = game.Version
+	i.UpToDate = game.UpToDate()
+	i.DownloadSize = game.BytesToDownload()
+	i.OutdatedComponents = make([]string, 0)
+	i.MissingFiles = make([]string, 0)
+	i.MismatchedFiles = make([]string, 0)
+	i.UnexpectedFiles = game.UnexpectedFiles
+
+	for _, c := range game.Components {
+		if !c.UpToDate() {
+			i.OutdatedComponents = append(i.OutdatedComponents, c.Component)
+		}
+
+		// Prefix the files with the component, the same file can be in multiple components.
+		for _, f := range c.MissingFiles {
+			i.MissingFiles = append(i.MissingFiles, c.Component+"/"+f)
+		}
+
+		for _, f := range c.MismatchedFiles {
+			i.MismatchedFiles = append(i.MismatchedFiles, c.Component+"/"+f)
+		}
+	}
+
+	return i
+}
+
+// watchInstances will update the instance model every time a game starts or exits.
+func (b *DiabloBridge) watchInstances() {
+	for instances := range b.d2service.WatchInstances() {
+		b.instanceModel.Clear()
+		for _, instance := range instances {
+			b.instanceModel.AddItem(newInstanceItem(instance))
+		}
+
+		// Instances change when a game crashes, the crash is in the history by then.
+		b.updateCrashes()
+	}
+}
+
+// updateCrashes will describe the latest crash of the session.
+func (b *DiabloBridge) updateCrashes() {
+	crashes := b.d2service.Crashes()
+	if len(crashes) == 0 {
+		b.SetLastCrash("")
+		return
+	}
+
+	crash := crashes[len(crashes)-1]
+
+	name := "Diablo II"
+	if crash.Label != "" {
+		name = crash.Label
+	}
+
+	status := fmt.Sprintf("%s crashed at %s with status %d", name, crash.ExitedAt.Format(time.Kitchen), crash.ExitCode)
+	if crash.Restarted {
+		status += ", restarting"
+	}
+
+	b.SetLastCrash(status)
+}
+
+// stopInstance will stop the game instance with the pid.
+func (b *DiabloBridge) stopInstance(pid int) {
+	// Stopping waits for the game to exit, don't lock the GUI.
+	go func() {
+		if err := b.d2service.StopInstance(pid); err != nil {
+			b.logger.Error(err)
+		}
+	}()
+}
+
+// stopGame will stop all instances of the game.
+func (b *DiabloBridge) stopGame(gameID string) {
+	go func() {
+		if err := b.d2service.StopGame(gameID); err != nil {
+			b.logger.Error(err)
+		}
+	}()
+}
+
+// stopAll will stop all game instances.
+func (b *DiabloBridge) stopAll() {
+	go func() {
+		if err := b.d2service.StopAll(); err != nil {
+			b.logger.Error(err)
+		}
+	}()
+}
+
+func newInstanceItem(instance d2.Instance) *d2.InstanceItem {
+	i := d2.NewInstanceItem(nil)
+	i.PID = instance.PID
+	i.GameID = instance.GameID
+	i.StartedAt = instance.StartedAt.Format(time.Kitchen)
+	i.Label = instance.Label
+
+	return i
+}
+
 func (b *DiabloBridge) applyDEP(path string) bool {
 	err := b.d2service.ApplyDEP(path)
 	if err != nil {
@@ -130,19 +341,26 @@ func (b *DiabloBridge) updateGateway(gateway string) {
 }
 
 // NewDiablo returns a new Diablo bridge with all dependencies set up.
-func NewDiablo(d2s d2.Service, gateway string, logger log.Logger) *DiabloBridge {
+func NewDiablo(d2s d2.Service, vm *d2.ValidationModel, im *d2.InstanceModel, gateway string, logger log.Logger) *DiabloBridge {
 	b := NewDiabloBridge(nil)
 
 	// Set dependencies.
 	b.d2service = d2s
 	b.logger = logger
 
+	// Setup models.
+	b.validationModel = vm
+	b.SetValidation(vm)
+	b.instanceModel = im
+	b.SetInstances(im)
+
 	// Set initial state.
 	b.SetPatching(false)
 	b.SetErrored(false)
 	b.SetValidVersion(false)
 	b.SetValidatingVersion(false)
 	b.SetGateway(gateway)
+	b.SetLaunching(false)
 
 	return b
 }
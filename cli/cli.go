@@ -0,0 +1,269 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+
+	"github.com/nokka/slashdiablo-launcher/config"
+	"github.com/nokka/slashdiablo-launcher/d2"
+)
+
+// Exit codes returned by Run.
+const (
+	// ExitOK is returned when the command succeeded.
+	ExitOK = 0
+
+	// ExitError is returned when the command failed.
+	ExitError = 1
+
+	// ExitUsage is returned when the command was used incorrectly.
+	ExitUsage = 2
+
+	// ExitOutdated is returned by validate when the games need to be patched.
+	ExitOutdated = 3
+)
+
+// commands are the subcommands available in headless mode.
+var commands = map[string]string{
+	"patch":    "Patch all configured games to the current Slashdiablo version",
+	"validate": "Check if all configured games are up to date",
+	"launch":   "Launch all configured games",
+	"gateway":  "Set the Battle.net gateway, usage: gateway <Slashdiablo|Battle.net|custom gateway>",
+}
+
+// IsCommand returns true if the given argument is a headless subcommand.
+func IsCommand(arg string) bool {
+	_, ok := commands[arg]
+	return ok
+}
+
+// Runner runs headless commands against the Diablo II service.
+type Runner struct {
+	d2service     d2.Service
+	configService config.Service
+	out           io.Writer
+	errOut        io.Writer
+}
+
+// Run will execute the command in args and return the exit code.
+func (r *Runner) Run(args []string) int {
+	if len(args) == 0 || !IsCommand(args[0]) {
+		r.usage()
+		return ExitUsage
+	}
+
+	switch args[0] {
+	case "patch":
+		return r.patch()
+	case "validate":
+		return r.validate()
+	case "launch":
+		return r.launch()
+	case "gateway":
+		if len(args) != 2 {
+			r.usage()
+			return ExitUsage
+		}
+		return r.gateway(args[1])
+	}
+
+	return ExitUsage
+}
+
+func (r *Runner) patch() int {
+	// Cancel the patch on interrupt, the patcher cleans up after itself.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	defer signal.Stop(interrupt)
+
+	go func() {
+		select {
+		case <-interrupt:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	done := make(chan bool, 1)
+
+	// Let the patcher run, it returns a channel
+	// where we get the progress from, and another channel with errors.
+	progress, state := r.d2service.Patch(ctx, done)
+
+	// Only print progress when the whole percentage changes,
+	// otherwise we'd print on every write cycle.
+	lastPercentage := -1
+
+	for {
+		select {
+		case p, ok := <-progress:
+			// Stop listening once the patcher has stopped.
+			if !ok {
+				progress = nil
+				continue
+			}
+
+			percentage := int(p * 100)
+			if percentage != lastPercentage {
+				lastPercentage = percentage
+				fmt.Fprintf(r.out, "\r%3d%%", percentage)
+			}
+		case current, ok := <-state:
+			// The patcher stopped, done is sent before it stops so it was cancelled unless done is waiting.
+			if !ok {
+				select {
+				case <-done:
+					fmt.Fprintln(r.out, "\nGames are up to date")
+					return ExitOK
+				default:
+				}
+
+				fmt.Fprintln(r.errOut, "\npatch cancelled")
+				return ExitError
+			}
+
+			if current.Error != nil {
+				fmt.Fprintf(r.errOut, "\n%s: %s\n", current.Message, current.Error)
+				return ExitError
+			}
+
+			if current.Message != "" {
+				// Progress resets when a new message arrives.
+				lastPercentage = -1
+				fmt.Fprintf(r.out, "\n%s\n", current.Message)
+			}
+		case <-done:
+			fmt.Fprintln(r.out, "\nGames are up to date")
+			return ExitOK
+		}
+	}
+}
+
+func (r *Runner) validate() int {
+	report, err := r.d2service.ValidateGameVersions(context.Background())
+	if err != nil {
+		fmt.Fprintf(r.errOut, "validation failed: %s\n", err)
+		return ExitError
+	}
+
+	for _, game := range report.Games {
+		r.printGameReport(game)
+	}
+
+	if !report.UpToDate() {
+		fmt.Fprintln(r.out, "Games need to be updated")
+		return ExitOutdated
+	}
+
+	fmt.Fprintln(r.out, "Games are up to date")
+	return ExitOK
+}
+
+func (r *Runner) printGameReport(game d2.GameReport) {
+	version := game.Version
+	if version == "" {
+		version = "unknown version"
+	}
+
+	if game.UpToDate() {
+		fmt.Fprintf(r.out, "%s (%s): up to date\n", game.Location, version)
+		return
+	}
+
+	fmt.Fprintf(r.out, "%s (%s): %d bytes to download\n", game.Location, version, game.BytesToDownload())
+
+	for _, c := range game.Components {
+		if c.UpToDate() {
+			continue
+		}
+
+		fmt.Fprintf(r.out, "  %-8s %d missing, %d outdated\n", c.Component, len(c.MissingFiles), len(c.MismatchedFiles))
+	}
+
+	for _, f := range game.UnexpectedFiles {
+		fmt.Fprintf(r.out, "  %-8s %s\n", "leftover", f)
+	}
+}
+
+func (r *Runner) launch() int {
+	for state := range r.d2service.Exec(context.Background()) {
+		switch state.Status {
+		case d2.LaunchWaiting:
+			if state.Label != "" {
+				fmt.Fprintf(r.out, "%s: started %s, instance %d of %d (pid %d)\n", state.Location, state.Label, state.Instance, state.Instances, state.PID)
+			} else {
+				fmt.Fprintf(r.out, "%s: started instance %d of %d (pid %d)\n", state.Location, state.Instance, state.Instances, state.PID)
+			}
+		case d2.LaunchFailed:
+			fmt.Fprintf(r.errOut, "launch failed: %s\n", state.Error)
+			return ExitError
+		}
+	}
+
+	return ExitOK
+}
+
+func (r *Runner) gateway(gateway string) int {
+	conf, err := r.configService.Read()
+	if err != nil {
+		fmt.Fprintf(r.errOut, "reading config failed: %s\n", err)
+		return ExitError
+	}
+
+	// Custom gateways are only known by the config.
+	gateways := append([]string{}, d2.BuiltinGateways...)
+	for _, g := range conf.Gateways {
+		gateways = append(gateways, g.Name)
+	}
+
+	if !contains(gateways, gateway) {
+		fmt.Fprintf(r.errOut, "unknown gateway %s, expected one of: %s\n", gateway, strings.Join(gateways, ", "))
+		return ExitUsage
+	}
+
+	if err := r.d2service.SetGateway(gateway); err != nil {
+		fmt.Fprintf(r.errOut, "setting gateway failed: %s\n", err)
+		return ExitError
+	}
+
+	fmt.Fprintf(r.out, "Gateway set to %s\n", gateway)
+	return ExitOK
+}
+
+func (r *Runner) usage() {
+	fmt.Fprintln(r.errOut, "usage: slashdiablo-launcher [command]")
+	fmt.Fprintln(r.errOut, "\nRunning without a command starts the launcher window.")
+	fmt.Fprintln(r.errOut, "\ncommands:")
+
+	for _, name := range []string{"patch", "validate", "launch", "gateway"} {
+		fmt.Fprintf(r.errOut, "  %-10s %s\n", name, commands[name])
+	}
+}
+
+// contains reports if the name is in the list.
+func contains(list []string, name string) bool {
+	for _, n := range list {
+		if n == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// NewRunner returns a new runner with all dependencies set up.
+func NewRunner(d2s d2.Service, cs config.Service, out io.Writer, errOut io.Writer) *Runner {
+	return &Runner{
+		d2service:     d2s,
+		configService: cs,
+		out:           out,
+		errOut:        errOut,
+	}
+}
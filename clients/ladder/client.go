@@ -6,6 +6,14 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"strings"
+
+	"github.com/nokka/slashdiablo-launcher/clients/local"
+)
+
+const (
+	// DefaultAddress is the address of the Slashdiablo ladder API.
+	DefaultAddress = "https://ladder.slashdiablo.net"
 )
 
 // Client encapsulates the details of the Ladder API.
@@ -29,7 +37,17 @@ type ladderResponse struct {
 
 // GetLadder gets the top ladder characters for the given mode.
 func (c *Client) GetLadder(mode string) ([]Character, error) {
-	response, err := c.do(http.MethodGet, c.address+"/ladder/rankings/"+mode+"?class=overall", nil)
+	var (
+		response []byte
+		err      error
+	)
+
+	// Local mirrors store the overall rankings of each mode as a json file.
+	if local.IsFileURL(c.address) {
+		response, err = readLocal(c.address + "/ladder/rankings/" + mode + ".json")
+	} else {
+		response, err = c.do(http.MethodGet, c.address+"/ladder/rankings/"+mode+"?class=overall", nil)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -75,9 +93,20 @@ func (c *Client) do(method string, addr string, payload []byte) ([]byte, error)
 	return responseBody, nil
 }
 
-// NewClient returns a new ladder client with all dependencies.
-func NewClient() Client {
+// readLocal reads the file at the file URL.
+func readLocal(address string) ([]byte, error) {
+	path, err := local.Path(address)
+	if err != nil {
+		return nil, err
+	}
+
+	return ioutil.ReadFile(path)
+}
+
+// NewClient returns a new ladder client with all dependencies, the address is either
+// a http(s) URL or a file:// URL to a local mirror.
+func NewClient(address string) Client {
 	return Client{
-		address: "https://ladder.slashdiablo.net",
+		address: strings.TrimSuffix(address, "/"),
 	}
 }
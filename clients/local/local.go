@@ -0,0 +1,35 @@
+package local
+
+import (
+	"net/url"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+const (
+	// scheme is the URL scheme used for local directories.
+	scheme = "file://"
+)
+
+// IsFileURL returns true if the address points to a local directory.
+func IsFileURL(address string) bool {
+	return strings.HasPrefix(address, scheme)
+}
+
+// Path returns the path on disk for the file URL.
+func Path(address string) (string, error) {
+	u, err := url.Parse(address)
+	if err != nil {
+		return "", err
+	}
+
+	p := u.Path
+
+	// Windows drive letters are parsed as /C:/mirror, remove the leading slash.
+	if runtime.GOOS == "windows" && len(p) > 2 && p[0] == '/' && p[2] == ':' {
+		p = p[1:]
+	}
+
+	return filepath.FromSlash(p), nil
+}
@@ -1,9 +1,27 @@
 package slashdiablo
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
+
+	"github.com/nokka/slashdiablo-launcher/clients/local"
+)
+
+const (
+	// DefaultAddress is the address of the Slashdiablo file server.
+	DefaultAddress = "http://slashdiablo.net/files"
+)
+
+var (
+	// ErrRangeNotSatisfiable is used when the requested offset is beyond the end of the file.
+	ErrRangeNotSatisfiable = errors.New("requested range not satisfiable")
+
+	// ErrNotModified is used when the requested file hasn't changed since it was last fetched.
+	ErrNotModified = errors.New("not modified")
 )
 
 // Client encapsulates the details of the Slashdiablo API.
@@ -11,29 +29,138 @@ type Client struct {
 	address string
 }
 
-// GetFile will the file by the given path in the repository set on the service.
-func (c *Client) GetFile(filePath string) (io.ReadCloser, error) {
-	resp, err := http.Get(fmt.Sprintf("%s/slashdiablo-patches/%s", c.address, filePath))
+// GetFile will get the file by the given path in the repository set on the service,
+// starting at the given byte offset. The returned offset is where the contents start,
+// it will be 0 if the server doesn't support ranges and returns the whole file.
+func (c *Client) GetFile(ctx context.Context, filePath string, offset int64) (io.ReadCloser, int64, error) {
+	addr := fmt.Sprintf("%s/slashdiablo-patches/%s", c.address, filePath)
+
+	// Read straight from disk when using a local mirror.
+	if local.IsFileURL(c.address) {
+		if err := ctx.Err(); err != nil {
+			return nil, 0, err
+		}
+		return openLocalFile(addr, offset)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, addr, nil)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 
-	return resp.Body, nil
+	// Cancelling the context aborts the request, including reading the body.
+	req = req.WithContext(ctx)
+
+	// Only ask for the remaining bytes if we've got some of the file already.
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+		return resp.Body, offset, nil
+	case http.StatusRequestedRangeNotSatisfiable:
+		resp.Body.Close()
+		return nil, 0, ErrRangeNotSatisfiable
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, 0, newStatusError(resp)
+	}
+
+	return resp.Body, 0, nil
+}
+
+// Validators identify the version of a previously fetched file.
+type Validators struct {
+	ETag         string `json:"etag"`
+	LastModified string `json:"last_modified"`
+}
+
+// GetFileIfModified will get the file by the given path in the repository, unless it's unchanged
+// since it was fetched with the given validators. The validators of the returned file are returned
+// with it, if the file is unchanged ErrNotModified is returned.
+func (c *Client) GetFileIfModified(ctx context.Context, filePath string, validators Validators) (io.ReadCloser, Validators, error) {
+	addr := fmt.Sprintf("%s/slashdiablo-patches/%s", c.address, filePath)
+
+	// Read straight from disk when using a local mirror.
+	if local.IsFileURL(c.address) {
+		if err := ctx.Err(); err != nil {
+			return nil, Validators{}, err
+		}
+		return openLocalFileIfModified(addr, validators)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, addr, nil)
+	if err != nil {
+		return nil, Validators{}, err
+	}
+
+	// Let the server tell us if the file has changed since we last fetched it.
+	if validators.ETag != "" {
+		req.Header.Set("If-None-Match", validators.ETag)
+	}
+
+	if validators.LastModified != "" {
+		req.Header.Set("If-Modified-Since", validators.LastModified)
+	}
+
+	resp, err := http.DefaultClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, Validators{}, err
+	}
+
+	if resp.StatusCode == http.StatusNotModified {
+		resp.Body.Close()
+		return nil, validators, ErrNotModified
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, Validators{}, newStatusError(resp)
+	}
+
+	return resp.Body, Validators{
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+	}, nil
 }
 
 // GetNews will fetch the remote news source.
-func (c *Client) GetNews() (io.ReadCloser, error) {
-	resp, err := http.Get(fmt.Sprintf("%s/news.json", c.address))
+func (c *Client) GetNews(ctx context.Context) (io.ReadCloser, error) {
+	addr := fmt.Sprintf("%s/news.json", c.address)
+
+	// Read straight from disk when using a local mirror.
+	if local.IsFileURL(c.address) {
+		contents, _, err := openLocalFile(addr, 0)
+		return contents, err
+	}
+
+	req, err := http.NewRequest(http.MethodGet, addr, nil)
 	if err != nil {
 		return nil, err
 	}
 
+	resp, err := http.DefaultClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, newStatusError(resp)
+	}
+
 	return resp.Body, nil
 }
 
-// NewClient returns a new client with all dependencies setup.
-func NewClient() Client {
+// NewClient returns a new client with all dependencies setup, the address is either
+// a http(s) URL or a file:// URL to a local mirror with the same layout.
+func NewClient(address string) Client {
 	return Client{
-		address: "http://slashdiablo.net/files",
+		address: strings.TrimSuffix(address, "/"),
 	}
 }
@@ -0,0 +1,62 @@
+package slashdiablo
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+const (
+	// bodySnippetSize is the max number of bytes of the response body kept in errors.
+	bodySnippetSize = 256
+)
+
+// StatusError is used when the server responds with a non 2xx status code.
+type StatusError struct {
+	StatusCode int
+	URL        string
+	Body       string
+}
+
+// Error returns the description of the failed request.
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("unexpected status code %d from %s: %s", e.StatusCode, e.URL, e.Body)
+}
+
+// NotFound returns true if the requested file doesn't exist on the server.
+func (e *StatusError) NotFound() bool {
+	return e.StatusCode == http.StatusNotFound
+}
+
+// ServerError returns true if the server failed to handle the request.
+func (e *StatusError) ServerError() bool {
+	return e.StatusCode >= http.StatusInternalServerError
+}
+
+// IsNotFound returns true if the error is a status error for a missing file.
+func IsNotFound(err error) bool {
+	statusErr, ok := err.(*StatusError)
+	return ok && statusErr.NotFound()
+}
+
+// IsServerError returns true if the error is a status error for a failing server.
+func IsServerError(err error) bool {
+	statusErr, ok := err.(*StatusError)
+	return ok && statusErr.ServerError()
+}
+
+// newStatusError creates a status error from the response and closes the body.
+func newStatusError(resp *http.Response) error {
+	defer resp.Body.Close()
+
+	// Keep the start of the body, error pages usually explain what went wrong.
+	snippet, _ := ioutil.ReadAll(io.LimitReader(resp.Body, bodySnippetSize))
+
+	return &StatusError{
+		StatusCode: resp.StatusCode,
+		URL:        resp.Request.URL.String(),
+		Body:       strings.TrimSpace(string(snippet)),
+	}
+}
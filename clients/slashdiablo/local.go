@@ -0,0 +1,68 @@
+package slashdiablo
+
+import (
+	"io"
+	"net/http"
+	"os"
+
+	"github.com/nokka/slashdiablo-launcher/clients/local"
+)
+
+// openLocalFile opens the file at the file URL from the given offset, it
+// behaves like the server does for missing files and invalid offsets.
+func openLocalFile(address string, offset int64) (io.ReadCloser, int64, error) {
+	path, err := local.Path(address)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, 0, &StatusError{StatusCode: http.StatusNotFound, URL: address}
+		}
+		return nil, 0, err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, 0, err
+	}
+
+	if offset > info.Size() {
+		f.Close()
+		return nil, 0, ErrRangeNotSatisfiable
+	}
+
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		f.Close()
+		return nil, 0, err
+	}
+
+	return f, offset, nil
+}
+
+// openLocalFileIfModified opens the file at the file URL, unless its modification
+// time is the same as when it was last opened.
+func openLocalFileIfModified(address string, validators Validators) (io.ReadCloser, Validators, error) {
+	f, _, err := openLocalFile(address, 0)
+	if err != nil {
+		return nil, Validators{}, err
+	}
+
+	info, err := f.(*os.File).Stat()
+	if err != nil {
+		f.Close()
+		return nil, Validators{}, err
+	}
+
+	lastModified := info.ModTime().UTC().Format(http.TimeFormat)
+
+	if validators.LastModified == lastModified {
+		f.Close()
+		return nil, validators, ErrNotModified
+	}
+
+	return f, Validators{LastModified: lastModified}, nil
+}
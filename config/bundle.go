@@ -0,0 +1,385 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/nokka/slashdiablo-launcher/storage"
+)
+
+const (
+	// bundleVersion is the version of the bundles written by Export.
+	bundleVersion = 1
+
+	// bhCfgName is the maphack config overridden by games with OverrideBHCfg.
+	bhCfgName = "BH.cfg"
+)
+
+// ErrUnsupportedBundle is returned when importing a bundle written by a newer launcher, or not by a launcher at all.
+var ErrUnsupportedBundle = errors.New("unsupported launcher setup")
+
+// Bundle is a portable launcher setup, exported on one machine to be imported on another.
+type Bundle struct {
+	Version  int               `json:"version"`
+	Gateway  string            `json:"gateway"`
+	Games    []BundleGame      `json:"games"`
+	Gateways []storage.Gateway `json:"gateways"`
+}
+
+// BundleGame is a game in a bundle, with the BH.cfg it overrides maphack's with.
+type BundleGame struct {
+	storage.Game
+
+	BHCfg string `json:"bh_cfg,omitempty"`
+}
+
+// ImportRequest is the data used to import a bundle.
+type ImportRequest struct {
+	Path string `json:"path"`
+
+	// Remap replaces the start of the game locations in the bundle, such as C:\Games
+	// with the directory the games are kept in on this machine. Locations are matched
+	// regardless of the path separators they're written with.
+	Remap map[string]string `json:"remap"`
+}
+
+// Export will write the games and gateway of the active profile, and the custom gateways, to a bundle at the path.
+func (s *service) Export(path string) error {
+	conf, err := s.store.Read()
+	if err != nil {
+		return err
+	}
+
+	bundle := Bundle{
+		Version:  bundleVersion,
+		Gateway:  conf.Gateway,
+		Games:    make([]BundleGame, 0, len(conf.Games)),
+		Gateways: conf.Gateways,
+	}
+
+	for _, g := range conf.Games {
+		game := BundleGame{Game: g}
+
+		// The BH.cfg is only the user's own if it overrides the one from maphack.
+		if g.OverrideBHCfg {
+			contents, err := ioutil.ReadFile(filepath.Join(storage.LocalizePath(g.Location), bhCfgName))
+			if err != nil && !os.IsNotExist(err) {
+				return err
+			}
+
+			game.BHCfg = string(contents)
+		}
+
+		bundle.Games = append(bundle.Games, game)
+	}
+
+	body, err := json.MarshalIndent(bundle, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(storage.LocalizePath(path), body, 0644)
+}
+
+// Import will replace the games and gateway of the active profile with the ones in the bundle at the path,
+// and add the custom gateways that don't exist yet. Nothing is changed unless the whole bundle is valid.
+func (s *service) Import(request ImportRequest) error {
+	// Lock before we update the models preventing race conditions.
+	s.mutex.Lock()
+
+	// Unlock when we're done.
+	defer s.mutex.Unlock()
+
+	body, err := ioutil.ReadFile(storage.LocalizePath(request.Path))
+	if err != nil {
+		return err
+	}
+
+	var bundle Bundle
+	if err := json.Unmarshal(body, &bundle); err != nil {
+		return err
+	}
+
+	if bundle.Version < 1 || bundle.Version > bundleVersion {
+		return ErrUnsupportedBundle
+	}
+
+	for i := range bundle.Games {
+		bundle.Games[i].Location = remapLocation(bundle.Games[i].Location, request.Remap)
+	}
+
+	gateways, err := s.newGateways(bundle.Gateways)
+	if err != nil {
+		return err
+	}
+
+	if err := s.validateBundle(&bundle, gateways); err != nil {
+		return err
+	}
+
+	conf, err := s.store.Read()
+	if err != nil {
+		return err
+	}
+
+	// Stage the BH.cfg files first, a game shouldn't be set up without the config it overrides with,
+	// and the games keep the configs they have unless the setup is saved.
+	var staged []*stagedFile
+	defer func() {
+		for _, f := range staged {
+			f.discard()
+		}
+	}()
+
+	games := make([]storage.Game, 0, len(bundle.Games))
+	for _, g := range bundle.Games {
+		if g.OverrideBHCfg && g.BHCfg != "" {
+			f, err := stageFile(filepath.Join(storage.LocalizePath(g.Location), bhCfgName), []byte(g.BHCfg))
+			if err != nil {
+				return err
+			}
+
+			staged = append(staged, f)
+		}
+
+		games = append(games, g.Game)
+	}
+
+	conf.Games = games
+	conf.Gateways = append(conf.Gateways, gateways...)
+
+	if bundle.Gateway != "" {
+		conf.Gateway = bundle.Gateway
+	}
+
+	if err := s.store.Write(conf); err != nil {
+		return err
+	}
+
+	for _, f := range staged {
+		if err := f.commit(); err != nil {
+			return err
+		}
+	}
+
+	s.resetGameModel(conf.Games)
+
+	for _, gateway := range gateways {
+		g := NewGateway(nil)
+		g.Name = gateway.Name
+		g.Host = gateway.Host
+		g.Timezone = gateway.Timezone
+		g.Custom = true
+
+		s.gatewayModel.AddGateway(g)
+	}
+
+	return nil
+}
+
+// stagedFile is a file written next to the file it replaces, until it's committed.
+type stagedFile struct {
+	path   string
+	staged string
+}
+
+// stageFile will write the contents next to the file at the path, without replacing it.
+func stageFile(path string, contents []byte) (*stagedFile, error) {
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".import")
+	if err != nil {
+		return nil, err
+	}
+
+	f := &stagedFile{path: path, staged: tmp.Name()}
+
+	if _, err := tmp.Write(contents); err != nil {
+		tmp.Close()
+		f.discard()
+		return nil, err
+	}
+
+	if err := tmp.Close(); err != nil {
+		f.discard()
+		return nil, err
+	}
+
+	if err := os.Chmod(f.staged, 0644); err != nil {
+		f.discard()
+		return nil, err
+	}
+
+	return f, nil
+}
+
+// commit will replace the file with the staged one, the file it replaces is kept as a backup.
+func (f *stagedFile) commit() error {
+	backup := f.path + ".bak"
+
+	// Renaming can't replace files on every OS, remove the previous backup first.
+	if err := os.Remove(backup); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	existed := true
+	if err := os.Rename(f.path, backup); err != nil {
+		if !os.IsNotExist(err) {
+			return err
+		}
+		existed = false
+	}
+
+	if err := os.Rename(f.staged, f.path); err != nil {
+		// Put the file back the way it was.
+		if existed {
+			os.Rename(backup, f.path)
+		}
+		return err
+	}
+
+	f.staged = ""
+	return nil
+}
+
+// discard will remove the staged file, unless it was committed.
+func (f *stagedFile) discard() {
+	if f.staged != "" {
+		os.Remove(f.staged)
+	}
+}
+
+// newGateways returns the gateways in the bundle that don't exist yet.
+func (s *service) newGateways(gateways []storage.Gateway) ([]storage.Gateway, error) {
+	var added []storage.Gateway
+
+	for _, g := range gateways {
+		if g.Name == "" || g.Host == "" {
+			return nil, ErrInvalidGateway
+		}
+
+		if s.gatewayModel.IndexOf(g.Name) != -1 {
+			continue
+		}
+
+		added = append(added, g)
+	}
+
+	return added, nil
+}
+
+// validateBundle will check that the games in the bundle can be launched on this machine, and that
+// the gateway exists. Games without an ID are given one.
+func (s *service) validateBundle(bundle *Bundle, gateways []storage.Gateway) error {
+	ids := make(map[string]bool)
+
+	for i := range bundle.Games {
+		g := &bundle.Games[i]
+
+		if g.ID == "" {
+			g.ID = uuid.New().String()
+		}
+
+		if ids[g.ID] {
+			return fmt.Errorf("game %s is in the setup more than once", g.ID)
+		}
+
+		ids[g.ID] = true
+
+		info, err := os.Stat(storage.LocalizePath(g.Location))
+		if err != nil || !info.IsDir() {
+			return fmt.Errorf("game directory %s doesn't exist", g.Location)
+		}
+
+		if g.Instances < 1 {
+			return fmt.Errorf("game %s has no instances to launch", g.Location)
+		}
+
+		if err := g.ValidateRestartPolicy(); err != nil {
+			return err
+		}
+
+		if err := validateFlags(g.Flags, g.AllowUnknownFlags); err != nil {
+			return err
+		}
+
+		for _, p := range g.Profiles {
+			if err := validateFlags(p.Flags, g.AllowUnknownFlags); err != nil {
+				return err
+			}
+		}
+	}
+
+	if bundle.Gateway == "" || s.gatewayModel.IndexOf(bundle.Gateway) != -1 {
+		return nil
+	}
+
+	for _, g := range gateways {
+		if g.Name == bundle.Gateway {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("gateway %s doesn't exist", bundle.Gateway)
+}
+
+// remapLocation replaces the longest matching start of the location, the remapped
+// location is stored the way locations from the file dialogs are.
+func remapLocation(location string, remap map[string]string) string {
+	path := slashPath(location)
+
+	var from, fromPath string
+	for prefix := range remap {
+		p := slashPath(prefix)
+		if len(p) > len(fromPath) && hasPathPrefix(path, p) {
+			from, fromPath = prefix, p
+		}
+	}
+
+	if from == "" {
+		return location
+	}
+
+	to := storage.StoragePath(remap[from])
+
+	rest := strings.Trim(path[len(fromPath):], "/")
+	if rest == "" {
+		return to
+	}
+
+	return strings.TrimRight(to, "/") + "/" + rest
+}
+
+// slashPath returns the path with forward slashes, without a trailing slash and without the slash
+// stored paths have before the drive letter, so paths written on any OS can be compared.
+func slashPath(path string) string {
+	slashed := strings.Replace(path, `\`, "/", -1)
+
+	if len(slashed) >= 3 && slashed[0] == '/' && slashed[2] == ':' {
+		slashed = slashed[1:]
+	}
+
+	// The root keeps its slash.
+	if trimmed := strings.TrimRight(slashed, "/"); trimmed != "" {
+		slashed = trimmed
+	}
+
+	return slashed
+}
+
+// hasPathPrefix reports if the slashed path starts with the slashed prefix, as a whole directory.
+func hasPathPrefix(path string, prefix string) bool {
+	if prefix == "" || !strings.HasPrefix(path, prefix) {
+		return false
+	}
+
+	if len(path) == len(prefix) || strings.HasSuffix(prefix, "/") {
+		return true
+	}
+
+	return path[len(prefix)] == '/'
+}
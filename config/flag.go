@@ -0,0 +1,16 @@
+package config
+
+import (
+	"github.com/therecipe/qt/core"
+)
+
+// Flag represents a known Diablo II command line flag.
+type Flag struct {
+	core.QObject
+
+	Name        string `json:"name"`
+	Description string `json:"description"`
+
+	// Argument is the type of argument the flag takes, none if it can be toggled.
+	Argument string `json:"argument"`
+}
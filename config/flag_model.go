@@ -0,0 +1,85 @@
+package config
+
+import (
+	"github.com/therecipe/qt/core"
+)
+
+// Model Roles.
+const (
+	FlagName = int(core.Qt__UserRole) + 1<<iota
+	FlagDescription
+	FlagArgument
+)
+
+// FlagModel represents the known flags the user can launch games with.
+type FlagModel struct {
+	core.QAbstractListModel
+
+	_ func() `constructor:"init"`
+
+	_ map[int]*core.QByteArray `property:"roles"`
+	_ []*Flag                  `property:"flags"`
+
+	_ func(*Flag) `slot:"addFlag"`
+}
+
+func (m *FlagModel) init() {
+	m.SetRoles(map[int]*core.QByteArray{
+		FlagName:        core.NewQByteArray2("name", -1),
+		FlagDescription: core.NewQByteArray2("description", -1),
+		FlagArgument:    core.NewQByteArray2("argument", -1),
+	})
+
+	m.ConnectData(m.data)
+	m.ConnectRowCount(m.rowCount)
+	m.ConnectColumnCount(m.columnCount)
+	m.ConnectRoleNames(m.roleNames)
+	m.ConnectAddFlag(m.addFlag)
+}
+
+func (m *FlagModel) rowCount(*core.QModelIndex) int {
+	return len(m.Flags())
+}
+
+func (m *FlagModel) columnCount(*core.QModelIndex) int {
+	return 1
+}
+
+func (m *FlagModel) roleNames() map[int]*core.QByteArray {
+	return m.Roles()
+}
+
+func (m *FlagModel) data(index *core.QModelIndex, role int) *core.QVariant {
+	if !index.IsValid() {
+		return core.NewQVariant()
+	}
+
+	if index.Row() >= len(m.Flags()) {
+		return core.NewQVariant()
+	}
+
+	item := m.Flags()[index.Row()]
+
+	switch role {
+	case FlagName:
+		return core.NewQVariant1(item.Name)
+	case FlagDescription:
+		return core.NewQVariant1(item.Description)
+	case FlagArgument:
+		return core.NewQVariant1(item.Argument)
+	default:
+		return core.NewQVariant()
+	}
+}
+
+// addFlag adds a flag to the model.
+func (m *FlagModel) addFlag(f *Flag) {
+	m.BeginInsertRows(core.NewQModelIndex(), len(m.Flags()), len(m.Flags()))
+	m.SetFlags(append(m.Flags(), f))
+	m.EndInsertRows()
+}
+
+func init() {
+	FlagModel_QRegisterMetaType()
+	Flag_QRegisterMetaType()
+}
@@ -0,0 +1,90 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Arguments the known flags take.
+const (
+	// FlagArgumentNone is a flag without an argument, that can be toggled.
+	FlagArgumentNone = "none"
+
+	// FlagArgumentString is a flag followed by a text argument.
+	FlagArgumentString = "string"
+
+	// FlagArgumentInt is a flag followed by a number argument.
+	FlagArgumentInt = "int"
+)
+
+// KnownFlag is a Diablo II command line flag.
+type KnownFlag struct {
+	Name        string
+	Description string
+	Argument    string
+}
+
+// KnownFlags are the Diablo II command line flags, by the order they're shown in.
+var KnownFlags = []KnownFlag{
+	{Name: "-w", Description: "Run in windowed mode", Argument: FlagArgumentNone},
+	{Name: "-skiptobnet", Description: "Skip the intro and go straight to Battle.net", Argument: FlagArgumentNone},
+	{Name: "-3dfx", Description: "Use the Glide video mode", Argument: FlagArgumentNone},
+	{Name: "-opengl", Description: "Use the OpenGL video mode", Argument: FlagArgumentNone},
+	{Name: "-d3d", Description: "Use the Direct3D video mode", Argument: FlagArgumentNone},
+	{Name: "-nofixaspect", Description: "Don't keep the aspect ratio in full screen", Argument: FlagArgumentNone},
+	{Name: "-vsync", Description: "Enable vertical sync", Argument: FlagArgumentNone},
+	{Name: "-lq", Description: "Render in low quality", Argument: FlagArgumentNone},
+	{Name: "-ns", Description: "Disable all sound", Argument: FlagArgumentNone},
+	{Name: "-sndbkg", Description: "Keep playing sound in the background", Argument: FlagArgumentNone},
+	{Name: "-direct", Description: "Read game files from the directory instead of the MPQs", Argument: FlagArgumentNone},
+	{Name: "-txt", Description: "Load the text data files instead of the compiled ones", Argument: FlagArgumentNone},
+	{Name: "-title", Description: "Set the title of the window", Argument: FlagArgumentString},
+	{Name: "-gamma", Description: "Set the gamma level", Argument: FlagArgumentInt},
+}
+
+// knownFlag returns the known flag with the name, or nil if it isn't known.
+// Flags aren't case sensitive.
+func knownFlag(name string) *KnownFlag {
+	for i := range KnownFlags {
+		if strings.EqualFold(KnownFlags[i].Name, name) {
+			return &KnownFlags[i]
+		}
+	}
+
+	return nil
+}
+
+// validateFlags will check that the flags are known, and that the flags taking
+// an argument are followed by one. Unknown flags are skipped if they're allowed.
+func validateFlags(flags []string, allowUnknown bool) error {
+	for i := 0; i < len(flags); i++ {
+		flag := knownFlag(flags[i])
+		if flag == nil {
+			if allowUnknown {
+				continue
+			}
+
+			return fmt.Errorf("unknown launch flag %s", flags[i])
+		}
+
+		if flag.Argument == FlagArgumentNone {
+			continue
+		}
+
+		// The argument is the next flag.
+		if i+1 >= len(flags) || flags[i+1] == "" || knownFlag(flags[i+1]) != nil {
+			return fmt.Errorf("launch flag %s needs an argument", flag.Name)
+		}
+
+		i++
+
+		if flag.Argument == FlagArgumentInt {
+			if _, err := strconv.Atoi(flags[i]); err != nil {
+				return fmt.Errorf("launch flag %s needs a number, got %s", flag.Name, flags[i])
+			}
+		}
+	}
+
+	return nil
+}
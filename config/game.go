@@ -1,6 +1,7 @@
 package config
 
 import (
+	"github.com/nokka/slashdiablo-launcher/storage"
 	"github.com/therecipe/qt/core"
 )
 
@@ -8,11 +9,17 @@ import (
 type Game struct {
 	core.QObject
 
-	ID            string   `json:"id"`
-	Location      string   `json:"location"`
-	Instances     int      `json:"instances"`
-	Maphack       bool     `json:"maphack"`
-	OverrideBHCfg bool     `json:"override_bh_cfg"`
-	HD            bool     `json:"hd"`
-	Flags         []string `json:"flags"`
+	ID            string                    `json:"id"`
+	Location      string                    `json:"location"`
+	Instances     int                       `json:"instances"`
+	Maphack       bool                      `json:"maphack"`
+	OverrideBHCfg bool                      `json:"override_bh_cfg"`
+	HD            bool                      `json:"hd"`
+	Flags         []string                  `json:"flags"`
+	Profiles      []storage.InstanceProfile `json:"profiles"`
+	LaunchDelay   int                       `json:"launch_delay"`
+	RestartPolicy string                    `json:"restart_policy"`
+	MaxRestarts   int                       `json:"max_restarts"`
+
+	AllowUnknownFlags bool `json:"allow_unknown_flags"`
 }
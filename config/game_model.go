@@ -1,6 +1,7 @@
 package config
 
 import (
+	"github.com/nokka/slashdiablo-launcher/storage"
 	"github.com/therecipe/qt/core"
 )
 
@@ -13,6 +14,9 @@ const (
 	OverrideBHCfg
 	HD
 	Flags
+	LaunchDelay
+	Profiles
+	AllowUnknownFlags
 )
 
 // GameModel represents a Diablo game.
@@ -36,6 +40,10 @@ func (m *GameModel) init() {
 		OverrideBHCfg: core.NewQByteArray2("override_bh_config", -1),
 		HD:            core.NewQByteArray2("hd", -1),
 		Flags:         core.NewQByteArray2("flags", -1),
+		LaunchDelay:   core.NewQByteArray2("launch_delay", -1),
+		Profiles:      core.NewQByteArray2("profiles", -1),
+
+		AllowUnknownFlags: core.NewQByteArray2("allow_unknown_flags", -1),
 	})
 
 	m.ConnectData(m.data)
@@ -83,6 +91,12 @@ func (m *GameModel) data(index *core.QModelIndex, role int) *core.QVariant {
 		return core.NewQVariant1(item.HD)
 	case Flags:
 		return core.NewQVariant1(item.Flags)
+	case LaunchDelay:
+		return core.NewQVariant1(item.LaunchDelay)
+	case Profiles:
+		return profilesVariant(item.Profiles)
+	case AllowUnknownFlags:
+		return core.NewQVariant1(item.AllowUnknownFlags)
 	default:
 		return core.NewQVariant()
 	}
@@ -99,7 +113,20 @@ func (m *GameModel) addGame(g *Game) {
 func (m *GameModel) updateGame(index int) {
 	var fIndex = m.Index(0, 0, core.NewQModelIndex())
 	var lIndex = m.Index(index, 0, core.NewQModelIndex())
-	m.DataChanged(fIndex, lIndex, []int{Location, Instances, Maphack, OverrideBHCfg, HD, Flags})
+	m.DataChanged(fIndex, lIndex, []int{Location, Instances, Maphack, OverrideBHCfg, HD, Flags, LaunchDelay, Profiles, AllowUnknownFlags})
+}
+
+// profilesVariant returns the profiles as a list of maps, the way QML reads them.
+func profilesVariant(profiles []storage.InstanceProfile) *core.QVariant {
+	list := make([]*core.QVariant, 0, len(profiles))
+	for _, p := range profiles {
+		list = append(list, core.NewQVariant1(map[string]*core.QVariant{
+			"label": core.NewQVariant1(p.Label),
+			"flags": core.NewQVariant1(p.Flags),
+		}))
+	}
+
+	return core.NewQVariant1(list)
 }
 
 func (m *GameModel) removeGame(index int) {
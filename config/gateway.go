@@ -0,0 +1,17 @@
+package config
+
+import (
+	"github.com/therecipe/qt/core"
+)
+
+// Gateway represents a gateway the user can connect to.
+type Gateway struct {
+	core.QObject
+
+	Name     string `json:"name"`
+	Host     string `json:"host"`
+	Timezone int    `json:"timezone"`
+
+	// Custom is true for gateways added by the user, only those can be deleted.
+	Custom bool `json:"custom"`
+}
@@ -0,0 +1,108 @@
+package config
+
+import (
+	"github.com/therecipe/qt/core"
+)
+
+// Model Roles.
+const (
+	GatewayName = int(core.Qt__UserRole) + 1<<iota
+	GatewayHost
+	GatewayTimezone
+	GatewayCustom
+)
+
+// GatewayModel represents the gateways available to the user.
+type GatewayModel struct {
+	core.QAbstractListModel
+
+	_ func() `constructor:"init"`
+
+	_ map[int]*core.QByteArray `property:"roles"`
+	_ []*Gateway               `property:"gateways"`
+
+	_ func(*Gateway)        `slot:"addGateway"`
+	_ func(name string) int `slot:"indexOf"`
+}
+
+func (m *GatewayModel) init() {
+	m.SetRoles(map[int]*core.QByteArray{
+		GatewayName:     core.NewQByteArray2("name", -1),
+		GatewayHost:     core.NewQByteArray2("host", -1),
+		GatewayTimezone: core.NewQByteArray2("timezone", -1),
+		GatewayCustom:   core.NewQByteArray2("custom", -1),
+	})
+
+	m.ConnectData(m.data)
+	m.ConnectRowCount(m.rowCount)
+	m.ConnectColumnCount(m.columnCount)
+	m.ConnectRoleNames(m.roleNames)
+	m.ConnectAddGateway(m.addGateway)
+	m.ConnectIndexOf(m.indexOf)
+}
+
+func (m *GatewayModel) rowCount(*core.QModelIndex) int {
+	return len(m.Gateways())
+}
+
+func (m *GatewayModel) columnCount(*core.QModelIndex) int {
+	return 1
+}
+
+func (m *GatewayModel) roleNames() map[int]*core.QByteArray {
+	return m.Roles()
+}
+
+func (m *GatewayModel) data(index *core.QModelIndex, role int) *core.QVariant {
+	if !index.IsValid() {
+		return core.NewQVariant()
+	}
+
+	if index.Row() >= len(m.Gateways()) {
+		return core.NewQVariant()
+	}
+
+	item := m.Gateways()[index.Row()]
+
+	switch role {
+	case GatewayName:
+		return core.NewQVariant1(item.Name)
+	case GatewayHost:
+		return core.NewQVariant1(item.Host)
+	case GatewayTimezone:
+		return core.NewQVariant1(item.Timezone)
+	case GatewayCustom:
+		return core.NewQVariant1(item.Custom)
+	default:
+		return core.NewQVariant()
+	}
+}
+
+// addGateway adds a gateway to the model.
+func (m *GatewayModel) addGateway(g *Gateway) {
+	m.BeginInsertRows(core.NewQModelIndex(), len(m.Gateways()), len(m.Gateways()))
+	m.SetGateways(append(m.Gateways(), g))
+	m.EndInsertRows()
+}
+
+// indexOf returns the row of the gateway with the name, -1 if there's none.
+func (m *GatewayModel) indexOf(name string) int {
+	for i, g := range m.Gateways() {
+		if g.Name == name {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func (m *GatewayModel) removeGateway(index int) {
+	m.BeginRemoveRows(core.NewQModelIndex(), index, index)
+	m.SetGateways(append(m.Gateways()[:index], m.Gateways()[index+1:]...))
+	m.EndRemoveRows()
+}
+
+func init() {
+	GatewayModel_QRegisterMetaType()
+	Gateway_QRegisterMetaType()
+}
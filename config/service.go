@@ -1,12 +1,38 @@
 package config
 
 import (
+	"errors"
+	"sort"
+	"strings"
 	"sync"
 
 	"github.com/google/uuid"
 	"github.com/nokka/slashdiablo-launcher/storage"
 )
 
+var (
+	// ErrGatewayExists is returned when adding a gateway with a name that's already taken.
+	ErrGatewayExists = errors.New("gateway already exists")
+
+	// ErrInvalidGateway is returned when adding a gateway without a name or host.
+	ErrInvalidGateway = errors.New("gateway needs a name and a host")
+
+	// ErrGatewayNotFound is returned when deleting a custom gateway that doesn't exist.
+	ErrGatewayNotFound = errors.New("gateway not found")
+
+	// ErrGatewayInUse is returned when deleting a gateway that a profile is set to.
+	ErrGatewayInUse = errors.New("gateway is in use by a profile")
+
+	// ErrProfileExists is returned when adding a profile with a name that's already taken.
+	ErrProfileExists = errors.New("profile already exists")
+
+	// ErrProfileNotFound is returned when switching to a profile that doesn't exist.
+	ErrProfileNotFound = errors.New("profile not found")
+
+	// ErrInvalidProfile is returned when adding a profile without a name.
+	ErrInvalidProfile = errors.New("profile needs a name")
+)
+
 // Service is responsible for all things related to configuration.
 type Service interface {
 	// Read will read the configuration and return it.
@@ -26,12 +52,34 @@ type Service interface {
 
 	// UpdateGateway will update the gateway in the persistent store.
 	UpdateGateway(gateway string) error
+
+	// AddGateway adds a custom gateway to the gateway model and the persistent store.
+	AddGateway(request AddGatewayRequest) error
+
+	// DeleteGateway will delete a custom gateway from the gateway model and the persistent store.
+	DeleteGateway(name string) error
+
+	// ProfileNames returns the names of all profiles, including the active one.
+	ProfileNames() ([]string, error)
+
+	// AddProfile adds a profile without any games to the persistent store.
+	AddProfile(name string) error
+
+	// SwitchProfile will make the profile the active one, replacing the games in the game model.
+	SwitchProfile(name string) error
+
+	// Export will write a portable bundle of the launcher setup to the path.
+	Export(path string) error
+
+	// Import will replace the games and gateway with the ones in a bundle, after validating it.
+	Import(request ImportRequest) error
 }
 
 type service struct {
-	store     storage.Store
-	gameModel *GameModel
-	mutex     sync.Mutex
+	store        storage.Store
+	gameModel    *GameModel
+	gatewayModel *GatewayModel
+	mutex        sync.Mutex
 }
 
 // Read will read the configuration and return it.
@@ -66,13 +114,18 @@ func (s *service) AddGame() {
 
 // UpdateGameRequest is the data used to update a game in the game model.
 type UpdateGameRequest struct {
-	ID            string   `json:"id"`
-	Location      string   `json:"location"`
-	Instances     int      `json:"instances"`
-	Maphack       bool     `json:"maphack"`
-	OverrideBHCfg bool     `json:"override_bh_cfg"`
-	HD            bool     `json:"hd"`
-	Flags         []string `json:"flags"`
+	ID            string                    `json:"id"`
+	Location      string                    `json:"location"`
+	Instances     int                       `json:"instances"`
+	Maphack       bool                      `json:"maphack"`
+	OverrideBHCfg bool                      `json:"override_bh_cfg"`
+	HD            bool                      `json:"hd"`
+	Flags         []string                  `json:"flags"`
+	Profiles      []storage.InstanceProfile `json:"profiles"`
+	LaunchDelay   int                       `json:"launch_delay"`
+
+	// AllowUnknownFlags skips validating the flags.
+	AllowUnknownFlags bool `json:"allow_unknown_flags"`
 }
 
 // UpsertGame will upsert the game to the config.
@@ -83,6 +136,17 @@ func (s *service) UpsertGame(request UpdateGameRequest) error {
 	// Unlock when we're done.
 	defer s.mutex.Unlock()
 
+	// The flags are passed straight to the game, don't let through any we don't know.
+	if err := validateFlags(request.Flags, request.AllowUnknownFlags); err != nil {
+		return err
+	}
+
+	for _, p := range request.Profiles {
+		if err := validateFlags(p.Flags, request.AllowUnknownFlags); err != nil {
+			return err
+		}
+	}
+
 	// Updates game model with the new information.
 	var updatedIndex int
 	games := s.gameModel.Games()
@@ -95,6 +159,9 @@ func (s *service) UpsertGame(request UpdateGameRequest) error {
 			games[i].OverrideBHCfg = request.OverrideBHCfg
 			games[i].HD = request.HD
 			games[i].Flags = request.Flags
+			games[i].Profiles = request.Profiles
+			games[i].AllowUnknownFlags = request.AllowUnknownFlags
+			games[i].LaunchDelay = request.LaunchDelay
 		}
 	}
 
@@ -166,6 +233,12 @@ func (s *service) PersistGameModel() error {
 			OverrideBHCfg: games[i].OverrideBHCfg,
 			HD:            games[i].HD,
 			Flags:         games[i].Flags,
+			Profiles:      games[i].Profiles,
+			LaunchDelay:   games[i].LaunchDelay,
+			RestartPolicy: games[i].RestartPolicy,
+			MaxRestarts:   games[i].MaxRestarts,
+
+			AllowUnknownFlags: games[i].AllowUnknownFlags,
 		})
 	}
 
@@ -195,13 +268,263 @@ func (s *service) UpdateGateway(gateway string) error {
 	return nil
 }
 
+// AddGatewayRequest is the data used to add a custom gateway.
+type AddGatewayRequest struct {
+	Name     string `json:"name"`
+	Host     string `json:"host"`
+	Timezone int    `json:"timezone"`
+}
+
+// AddGateway will add the custom gateway to the config.
+func (s *service) AddGateway(request AddGatewayRequest) error {
+	// Lock before we update the model preventing race conditions.
+	s.mutex.Lock()
+
+	// Unlock when we're done.
+	defer s.mutex.Unlock()
+
+	if request.Name == "" || request.Host == "" {
+		return ErrInvalidGateway
+	}
+
+	// The model holds the built in gateways too, none of the names can be reused.
+	if s.gatewayModel.IndexOf(request.Name) != -1 {
+		return ErrGatewayExists
+	}
+
+	conf, err := s.store.Read()
+	if err != nil {
+		return err
+	}
+
+	conf.Gateways = append(conf.Gateways, storage.Gateway{
+		Name:     request.Name,
+		Host:     request.Host,
+		Timezone: request.Timezone,
+	})
+
+	err = s.store.Write(conf)
+	if err != nil {
+		return err
+	}
+
+	g := NewGateway(nil)
+	g.Name = request.Name
+	g.Host = request.Host
+	g.Timezone = request.Timezone
+	g.Custom = true
+
+	s.gatewayModel.AddGateway(g)
+
+	return nil
+}
+
+// DeleteGateway will delete the custom gateway from the config.
+func (s *service) DeleteGateway(name string) error {
+	// Lock before we update the model preventing race conditions.
+	s.mutex.Lock()
+
+	// Unlock when we're done.
+	defer s.mutex.Unlock()
+
+	// Read the config in order to update it.
+	conf, err := s.store.Read()
+	if err != nil {
+		return err
+	}
+
+	// Games can't connect to a gateway that doesn't exist, switch away from it first.
+	if conf.Gateway == name {
+		return ErrGatewayInUse
+	}
+
+	for _, p := range conf.Profiles {
+		if p.Gateway == name {
+			return ErrGatewayInUse
+		}
+	}
+
+	// Delete gateway from the config.
+	deleted := false
+	for i := 0; i < len(conf.Gateways); i++ {
+		if conf.Gateways[i].Name == name {
+			conf.Gateways = append(conf.Gateways[:i], conf.Gateways[i+1:]...)
+			deleted = true
+			break
+		}
+	}
+
+	if !deleted {
+		return ErrGatewayNotFound
+	}
+
+	err = s.store.Write(conf)
+	if err != nil {
+		return err
+	}
+
+	// Delete from the gateway model too, built in gateways stay.
+	gateways := s.gatewayModel.Gateways()
+	for i := 0; i < len(gateways); i++ {
+		if gateways[i].Name == name && gateways[i].Custom {
+			s.gatewayModel.removeGateway(i)
+			break
+		}
+	}
+
+	return nil
+}
+
+// ProfileNames returns the names of all profiles, sorted by name.
+func (s *service) ProfileNames() ([]string, error) {
+	conf, err := s.store.Read()
+	if err != nil {
+		return nil, err
+	}
+
+	names := []string{conf.ActiveProfile}
+	for _, p := range conf.Profiles {
+		names = append(names, p.Name)
+	}
+
+	sort.Strings(names)
+
+	return names, nil
+}
+
+// AddProfile will add an empty profile to the config, on the current gateway.
+func (s *service) AddProfile(name string) error {
+	// Lock before we update the config preventing race conditions.
+	s.mutex.Lock()
+
+	// Unlock when we're done.
+	defer s.mutex.Unlock()
+
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return ErrInvalidProfile
+	}
+
+	conf, err := s.store.Read()
+	if err != nil {
+		return err
+	}
+
+	if name == conf.ActiveProfile || profileIndex(conf.Profiles, name) != -1 {
+		return ErrProfileExists
+	}
+
+	conf.Profiles = append(conf.Profiles, storage.Profile{
+		Name:    name,
+		Games:   make([]storage.Game, 0),
+		Gateway: conf.Gateway,
+	})
+
+	return s.store.Write(conf)
+}
+
+// SwitchProfile will make the profile the active one, the games and gateway
+// of the previously active profile are kept in its profile.
+func (s *service) SwitchProfile(name string) error {
+	// Lock before we update the model preventing race conditions.
+	s.mutex.Lock()
+
+	// Unlock when we're done.
+	defer s.mutex.Unlock()
+
+	conf, err := s.store.Read()
+	if err != nil {
+		return err
+	}
+
+	if name == conf.ActiveProfile {
+		return nil
+	}
+
+	index := profileIndex(conf.Profiles, name)
+	if index == -1 {
+		return ErrProfileNotFound
+	}
+
+	next := conf.Profiles[index]
+
+	// Swap the active profile with the one switched to.
+	conf.Profiles[index] = storage.Profile{
+		Name:    conf.ActiveProfile,
+		Games:   conf.Games,
+		Gateway: conf.Gateway,
+	}
+
+	conf.ActiveProfile = next.Name
+	conf.Games = next.Games
+	conf.Gateway = next.Gateway
+
+	if conf.Games == nil {
+		conf.Games = make([]storage.Game, 0)
+	}
+
+	err = s.store.Write(conf)
+	if err != nil {
+		return err
+	}
+
+	// Replace the games in the model with the games of the profile.
+	s.resetGameModel(conf.Games)
+
+	return nil
+}
+
+// resetGameModel will replace the games in the game model.
+func (s *service) resetGameModel(games []storage.Game) {
+	items := make([]*Game, 0, len(games))
+	for _, g := range games {
+		items = append(items, GameFromStorage(g))
+	}
+
+	s.gameModel.BeginResetModel()
+	s.gameModel.SetGames(items)
+	s.gameModel.EndResetModel()
+}
+
+// profileIndex returns the index of the profile with the name, -1 if there's none.
+func profileIndex(profiles []storage.Profile, name string) int {
+	for i, p := range profiles {
+		if p.Name == name {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// GameFromStorage returns a game for the game model from the game in the config.
+func GameFromStorage(game storage.Game) *Game {
+	g := NewGame(nil)
+	g.ID = game.ID
+	g.Location = game.Location
+	g.Instances = game.Instances
+	g.Maphack = game.Maphack
+	g.OverrideBHCfg = game.OverrideBHCfg
+	g.HD = game.HD
+	g.Flags = game.Flags
+	g.Profiles = game.Profiles
+	g.AllowUnknownFlags = game.AllowUnknownFlags
+	g.LaunchDelay = game.LaunchDelay
+	g.RestartPolicy = game.RestartPolicy
+	g.MaxRestarts = game.MaxRestarts
+
+	return g
+}
+
 // NewService returns a service with all the dependencies.
 func NewService(
 	store storage.Store,
 	gameModel *GameModel,
+	gatewayModel *GatewayModel,
 ) Service {
 	return &service{
-		store:     store,
-		gameModel: gameModel,
+		store:        store,
+		gameModel:    gameModel,
+		gatewayModel: gatewayModel,
 	}
 }
@@ -3,24 +3,26 @@
 package d2
 
 import (
-	"fmt"
+	"errors"
 	"os"
+	"os/exec"
+	"syscall"
+	"time"
 )
 
-// validate113cVersion will check the given installations Diablo II version.
-func validate113cVersion(dir string) (bool, error) {
-	return true, nil
+// gameCommand returns the command executing the Diablo II.exe in the given directory.
+func gameCommand(path string, flags []string, wine wineConfig) (*exec.Cmd, error) {
+	return nil, errors.New("launching Diablo II isn't supported on macOS")
 }
 
-// launch will execute the Diablo II.exe in the given directory.
-func launch(path string, flags []string, done chan execState) (*int, error) {
-	pid := 1
-	return &pid, nil
+// terminate will ask the process to exit.
+func terminate(process *os.Process) error {
+	return process.Signal(syscall.SIGTERM)
 }
 
-// localizePath will localize the path for the OS.
-func localizePath(path string) string {
-	return path
+// newReadinessChecker returns the readiness checker used on macOS.
+func newReadinessChecker(delay time.Duration) ReadinessChecker {
+	return &aliveChecker{duration: delay}
 }
 
 // configureForOS will set specific configurations, such as compatibility mode.
@@ -33,41 +35,7 @@ func applyDEP(path string) error {
 	return nil
 }
 
-func isHDInstalled(path string) (bool, error) {
-	filePath := localizePath(fmt.Sprintf("%s/%s", path, "D2HD.dll"))
-
-	// Check if the file exists on disk.
-	_, err := os.Stat(filePath)
-	if err != nil {
-		// File didn't exist on disk, return false.
-		if os.IsNotExist(err) {
-			return false, nil
-		}
-		// Unknown error.
-		return false, err
-	}
-
-	return true, nil
-}
-
-func isMaphackInstalled(path string) (bool, error) {
-	filePath := localizePath(fmt.Sprintf("%s/%s", path, "BH.dll"))
-
-	// Check if the file exists on disk.
-	_, err := os.Stat(filePath)
-	if err != nil {
-		// File didn't exist on disk, return false.
-		if os.IsNotExist(err) {
-			return false, nil
-		}
-		// Unknown error.
-		return false, err
-	}
-
-	return true, nil
-}
-
 // setGateway will set the gateway for Diablo II.
-func setGateway(gateway string) error {
+func setGateway(gatewayHex []byte, bnetIP string, wine wineConfig) error {
 	return nil
 }
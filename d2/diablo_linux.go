@@ -2,20 +2,40 @@
 
 package d2
 
-// validate113cVersion will check the given installations Diablo II version.
-func validate113cVersion(dir string) (bool, error) {
-	return false, nil
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"syscall"
+	"time"
+)
+
+// gameCommand returns the command executing the Diablo II.exe in the given directory through Wine.
+func gameCommand(path string, flags []string, wine wineConfig) (*exec.Cmd, error) {
+	args := append([]string{"Diablo II.exe"}, flags...)
+
+	// Exec the Diablo II.exe with the given command line args.
+	cmd := exec.Command(wine.Binary, args...)
+	cmd.Dir = path
+
+	// Run in the configured prefix, otherwise Wine uses its default prefix.
+	cmd.Env = os.Environ()
+	if wine.Prefix != "" {
+		cmd.Env = append(cmd.Env, fmt.Sprintf("WINEPREFIX=%s", wine.Prefix))
+	}
+
+	return cmd, nil
 }
 
-// launch will execute the Diablo II.exe in the given directory.
-func launch(path string, flags []string, done chan execState) (*int, error) {
-	id := 1
-	return &id, nil
+// terminate will ask the process to exit.
+func terminate(process *os.Process) error {
+	return process.Signal(syscall.SIGTERM)
 }
 
-// localizePath will localize the path for the OS.
-func localizePath(path string) string {
-	return path
+// newReadinessChecker returns the readiness checker used on Linux, the windows of games
+// running through Wine can't be found so they're ready once they've stayed alive for the delay.
+func newReadinessChecker(delay time.Duration) ReadinessChecker {
+	return &aliveChecker{duration: delay}
 }
 
 // configureForOS will set specific configurations, such as compatibility mode.
@@ -28,15 +48,24 @@ func applyDEP(path string) error {
 	return nil
 }
 
-func isHDInstalled(path string) (bool, error) {
-	return false, nil
-}
+// setGateway will set the gateway for Diablo II in the registry of the Wine prefix.
+func setGateway(gatewayHex []byte, bnetIP string, wine wineConfig) error {
+	prefix, err := wine.prefixPath()
+	if err != nil {
+		return err
+	}
 
-func isMaphackInstalled(path string) (bool, error) {
-	return false, nil
-}
+	reg, err := openWineRegistry(prefix)
+	if err != nil {
+		return err
+	}
 
-// setGateway will set the gateway for Diablo II.
-func setGateway(gateway string) error {
-	return nil
+	// Set the gateway hex.
+	reg.setBinary(`Software\Battle.net\Configuration`, "Diablo II Battle.net Gateways", gatewayHex)
+
+	// Set Battle.net IP and the command line args when starting.
+	reg.setString(`Software\Blizzard Entertainment\Diablo II`, "BNETIP", bnetIP)
+	reg.setString(`Software\Blizzard Entertainment\Diablo II`, "CmdLine", "-skiptobnet")
+
+	return reg.save()
 }
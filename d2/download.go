@@ -0,0 +1,96 @@
+package d2
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+)
+
+const (
+	// defaultDownloadWorkers is the number of files downloaded at once, unless configured.
+	defaultDownloadWorkers = 4
+)
+
+// downloadFiles will download the patch files into .tmp files in the given path using a
+// bounded number of workers, the first failing download cancels the remaining ones.
+func (s *service) downloadFiles(ctx context.Context, files []PatchFile, remoteDir string, path string, counter *WriteCounter) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	workers := s.downloadWorkers()
+
+	var (
+		jobs = make(chan PatchFile)
+		errs = make(chan error, workers)
+		wg   sync.WaitGroup
+	)
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for file := range jobs {
+				// Create the file, but give it a tmp file extension, this means we won't overwrite a
+				// file until it's downloaded, but we'll remove the tmp extension once downloaded.
+				tmpPath := localizePath(fmt.Sprintf("%s/%s.tmp", path, file.Name))
+
+				if err := s.downloadVerifiedFile(ctx, file, remoteDir, tmpPath, counter); err != nil {
+					// Every worker reports at most one error, so this never blocks.
+					errs <- err
+					cancel()
+					return
+				}
+			}
+		}()
+	}
+
+	// Hand out the files to the workers until they're done or one of them failed.
+feed:
+	for _, file := range files {
+		select {
+		case jobs <- file:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+
+	close(jobs)
+	wg.Wait()
+	close(errs)
+
+	// The first error is the one that cancelled the others.
+	if err := <-errs; err != nil {
+		return err
+	}
+
+	// Cancelled between files, the files that weren't handed out haven't been downloaded.
+	return ctx.Err()
+}
+
+// downloadWorkers returns the configured number of concurrent downloads.
+func (s *service) downloadWorkers() int {
+	conf, err := s.configService.Read()
+	if err != nil || conf.DownloadWorkers <= 0 {
+		return defaultDownloadWorkers
+	}
+
+	return conf.DownloadWorkers
+}
+
+// contextReader stops reading as soon as the context is done.
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+// Read reads from the underlying reader unless the context is done.
+func (cr *contextReader) Read(p []byte) (int, error) {
+	if err := cr.ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	return cr.r.Read(p)
+}
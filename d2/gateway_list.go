@@ -0,0 +1,103 @@
+package d2
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	// gatewayListVersion is the version of the gateway list format written by Diablo II.
+	gatewayListVersion = "1002"
+)
+
+// Gateway is a realm Diablo II can connect to.
+type Gateway struct {
+	Host     string
+	Timezone int
+	Name     string
+}
+
+// GatewayList is the list of gateways Diablo II stores in the registry as
+// "Diablo II Battle.net Gateways", a list of null terminated strings.
+type GatewayList struct {
+	Version string
+
+	// Default is the 1-based index of the gateway selected by default.
+	Default int
+
+	Gateways []Gateway
+}
+
+// Marshal will encode the list in the registry format, the version and default
+// index are followed by the host, timezone and name of every gateway.
+func (l *GatewayList) Marshal() ([]byte, error) {
+	version := l.Version
+	if version == "" {
+		version = gatewayListVersion
+	}
+
+	fields := []string{version, fmt.Sprintf("%02d", l.Default)}
+
+	for _, g := range l.Gateways {
+		fields = append(fields, g.Host, strconv.Itoa(g.Timezone), g.Name)
+	}
+
+	var b bytes.Buffer
+
+	for _, f := range fields {
+		if strings.ContainsRune(f, 0) {
+			return nil, fmt.Errorf("invalid gateway list: %q contains a null character", f)
+		}
+
+		b.WriteString(f)
+		b.WriteByte(0)
+	}
+
+	// The list is terminated by an empty string.
+	b.WriteByte(0)
+
+	return b.Bytes(), nil
+}
+
+// Unmarshal will decode the list from the registry format.
+func (l *GatewayList) Unmarshal(data []byte) error {
+	// Every field is null terminated, and the list ends with an empty field.
+	if !bytes.HasSuffix(data, []byte{0, 0}) {
+		return errors.New("invalid gateway list: missing terminator")
+	}
+
+	fields := strings.Split(string(data[:len(data)-2]), "\x00")
+	if len(fields) < 2 || (len(fields)-2)%3 != 0 {
+		return fmt.Errorf("invalid gateway list: unexpected number of fields %d", len(fields))
+	}
+
+	def, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return fmt.Errorf("invalid gateway list: default index %q", fields[1])
+	}
+
+	list := GatewayList{
+		Version: fields[0],
+		Default: def,
+	}
+
+	for i := 2; i < len(fields); i += 3 {
+		timezone, err := strconv.Atoi(fields[i+1])
+		if err != nil {
+			return fmt.Errorf("invalid gateway list: timezone %q", fields[i+1])
+		}
+
+		list.Gateways = append(list.Gateways, Gateway{
+			Host:     fields[i],
+			Timezone: timezone,
+			Name:     fields[i+2],
+		})
+	}
+
+	*l = list
+
+	return nil
+}
@@ -0,0 +1,143 @@
+package d2
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+// slashGatewayList is the realm list of Slashdiablo, the way Diablo II stores it in the registry.
+var slashGatewayList = []byte{
+	0x31, 0x30, 0x30, 0x32,
+	0x00, 0x30, 0x31, 0x00,
+	0x70, 0x6c, 0x61, 0x79,
+	0x2e, 0x73, 0x6c, 0x61,
+	0x73, 0x68, 0x64, 0x69,
+	0x61, 0x62, 0x6c, 0x6f,
+	0x2e, 0x6e, 0x65, 0x74,
+	0x00, 0x2d, 0x36, 0x00,
+	0x73, 0x6c, 0x61, 0x73,
+	0x68, 0x00, 0x65, 0x76,
+	0x6e, 0x74, 0x2e, 0x73,
+	0x6c, 0x61, 0x73, 0x68,
+	0x64, 0x69, 0x61, 0x62,
+	0x6c, 0x6f, 0x2e, 0x6e,
+	0x65, 0x74, 0x00, 0x38,
+	0x00, 0x45, 0x76, 0x65,
+	0x6e, 0x74, 0x00, 0x00,
+}
+
+// battleNetGatewayList is the realm list of the original Battle.net, the way Diablo II stores it in the registry.
+var battleNetGatewayList = []byte{
+	0x31, 0x30, 0x30, 0x32,
+	0x00, 0x30, 0x31, 0x00,
+	0x75, 0x73, 0x77, 0x65,
+	0x73, 0x74, 0x2e, 0x62,
+	0x61, 0x74, 0x74, 0x6c,
+	0x65, 0x2e, 0x6e, 0x65,
+	0x74, 0x00, 0x38, 0x00,
+	0x55, 0x2e, 0x53, 0x2e,
+	0x20, 0x57, 0x65, 0x73,
+	0x74, 0x00, 0x75, 0x73,
+	0x65, 0x61, 0x73, 0x74,
+	0x2e, 0x62, 0x61, 0x74,
+	0x74, 0x6c, 0x65, 0x2e,
+	0x6e, 0x65, 0x74, 0x00,
+	0x36, 0x00, 0x55, 0x2e,
+	0x53, 0x2e, 0x20, 0x45,
+	0x61, 0x73, 0x74, 0x00,
+	0x61, 0x73, 0x69, 0x61,
+	0x2e, 0x62, 0x61, 0x74,
+	0x74, 0x6c, 0x65, 0x2e,
+	0x6e, 0x65, 0x74, 0x00,
+	0x2d, 0x39, 0x00, 0x41,
+	0x73, 0x69, 0x61, 0x00,
+	0x65, 0x75, 0x72, 0x6f,
+	0x70, 0x65, 0x2e, 0x62,
+	0x61, 0x74, 0x74, 0x6c,
+	0x65, 0x2e, 0x6e, 0x65,
+	0x74, 0x00, 0x2d, 0x31,
+	0x00, 0x45, 0x75, 0x72,
+	0x6f, 0x70, 0x65, 0x00,
+	0x00,
+}
+
+func TestGatewayListMarshal(t *testing.T) {
+	tests := []struct {
+		name   string
+		realms []Gateway
+		want   []byte
+	}{
+		{name: GatewaySlashdiablo, realms: slashRealms, want: slashGatewayList},
+		{name: GatewayBattleNet, realms: battleNetRealms, want: battleNetGatewayList},
+	}
+
+	for _, tt := range tests {
+		list := GatewayList{Version: gatewayListVersion, Default: 1, Gateways: tt.realms}
+
+		got, err := list.Marshal()
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %s", tt.name, err)
+		}
+
+		if !bytes.Equal(got, tt.want) {
+			t.Errorf("%s: got % x, want % x", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestGatewayListUnmarshal(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want GatewayList
+	}{
+		{
+			name: GatewaySlashdiablo,
+			data: slashGatewayList,
+			want: GatewayList{Version: gatewayListVersion, Default: 1, Gateways: slashRealms},
+		},
+		{
+			name: GatewayBattleNet,
+			data: battleNetGatewayList,
+			want: GatewayList{Version: gatewayListVersion, Default: 1, Gateways: battleNetRealms},
+		},
+	}
+
+	for _, tt := range tests {
+		var list GatewayList
+		if err := list.Unmarshal(tt.data); err != nil {
+			t.Fatalf("%s: unexpected error: %s", tt.name, err)
+		}
+
+		if !reflect.DeepEqual(list, tt.want) {
+			t.Errorf("%s: got %+v, want %+v", tt.name, list, tt.want)
+		}
+
+		// Encoding the decoded list has to give back the exact same bytes.
+		got, err := list.Marshal()
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %s", tt.name, err)
+		}
+
+		if !bytes.Equal(got, tt.data) {
+			t.Errorf("%s: round trip got % x, want % x", tt.name, got, tt.data)
+		}
+	}
+}
+
+func TestGatewayListUnmarshalInvalid(t *testing.T) {
+	tests := map[string][]byte{
+		"missing terminator": []byte("1002\x0001\x00"),
+		"missing fields":     []byte("1002\x0001\x00play.slashdiablo.net\x00\x00"),
+		"invalid default":    []byte("1002\x00xx\x00\x00"),
+		"invalid timezone":   []byte("1002\x0001\x00play.slashdiablo.net\x00xx\x00slash\x00\x00"),
+	}
+
+	for name, data := range tests {
+		var list GatewayList
+		if err := list.Unmarshal(data); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
@@ -1,5 +1,11 @@
 package d2
 
+import (
+	"fmt"
+
+	"github.com/nokka/slashdiablo-launcher/storage"
+)
+
 const (
 	// GatewaySlashdiablo is the gateway option used if the user wants to connect to Slashdiablo.
 	GatewaySlashdiablo = "Slashdiablo"
@@ -13,3 +19,54 @@ const (
 	// BattleNetIP is the default Battle.net IP.
 	BattleNetIP = "uswest.battle.net"
 )
+
+// BuiltinGateways are the gateways available without being configured, custom gateways can't use their names.
+var BuiltinGateways = []string{GatewaySlashdiablo, GatewayBattleNet}
+
+// slashRealms are the realms of Slashdiablo.
+var slashRealms = []Gateway{
+	{Host: "play.slashdiablo.net", Timezone: -6, Name: "slash"},
+	{Host: "evnt.slashdiablo.net", Timezone: 8, Name: "Event"},
+}
+
+// battleNetRealms are the realms of the original Battle.net.
+var battleNetRealms = []Gateway{
+	{Host: "uswest.battle.net", Timezone: 8, Name: "U.S. West"},
+	{Host: "useast.battle.net", Timezone: 6, Name: "U.S. East"},
+	{Host: "asia.battle.net", Timezone: -9, Name: "Asia"},
+	{Host: "europe.battle.net", Timezone: -1, Name: "Europe"},
+}
+
+// gatewayValues returns the encoded realm list and Battle.net IP Diablo II is configured with for the gateway.
+// The realm list holds the realms of every gateway, with the first realm of the given gateway selected.
+func gatewayValues(gateway string, custom []storage.Gateway) ([]byte, string, error) {
+	list := GatewayList{Version: gatewayListVersion}
+	var bnetIP string
+
+	add := func(name string, realms []Gateway, ip string) {
+		if name == gateway {
+			list.Default = len(list.Gateways) + 1
+			bnetIP = ip
+		}
+
+		list.Gateways = append(list.Gateways, realms...)
+	}
+
+	add(GatewaySlashdiablo, slashRealms, SlashDiabloIP)
+	add(GatewayBattleNet, battleNetRealms, BattleNetIP)
+
+	for _, g := range custom {
+		add(g.Name, []Gateway{{Host: g.Host, Timezone: g.Timezone, Name: g.Name}}, g.Host)
+	}
+
+	if list.Default == 0 {
+		return nil, "", fmt.Errorf("unknown gateway %q", gateway)
+	}
+
+	gatewayHex, err := list.Marshal()
+	if err != nil {
+		return nil, "", err
+	}
+
+	return gatewayHex, bnetIP, nil
+}
@@ -0,0 +1,36 @@
+package d2
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/nokka/slashdiablo-launcher/storage"
+)
+
+func isHDInstalled(path string) (bool, error) {
+	return fileExists(localizePath(fmt.Sprintf("%s/%s", path, "D2HD.dll")))
+}
+
+func isMaphackInstalled(path string) (bool, error) {
+	return fileExists(localizePath(fmt.Sprintf("%s/%s", path, "BH.dll")))
+}
+
+// fileExists will check if the file exists on disk.
+func fileExists(filePath string) (bool, error) {
+	_, err := os.Stat(filePath)
+	if err != nil {
+		// File didn't exist on disk, return false.
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		// Unknown error.
+		return false, err
+	}
+
+	return true, nil
+}
+
+// localizePath will localize the path for the OS.
+func localizePath(path string) string {
+	return storage.LocalizePath(path)
+}
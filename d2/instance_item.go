@@ -0,0 +1,13 @@
+package d2
+
+import "github.com/therecipe/qt/core"
+
+// InstanceItem represents a running game instance in the model.
+type InstanceItem struct {
+	core.QObject
+
+	PID       int
+	GameID    string
+	StartedAt string
+	Label     string
+}
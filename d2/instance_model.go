@@ -0,0 +1,97 @@
+package d2
+
+import (
+	"github.com/therecipe/qt/core"
+)
+
+// Model Roles.
+const (
+	InstancePID = int(core.Qt__UserRole) + 1<<iota
+	InstanceGameID
+	InstanceStartedAt
+	InstanceLabel
+)
+
+// InstanceModel is the model of the running game instances.
+type InstanceModel struct {
+	core.QAbstractListModel
+
+	_ func() `constructor:"init"`
+
+	_ map[int]*core.QByteArray `property:"roles"`
+	_ []*InstanceItem          `property:"items"`
+
+	_ func(*InstanceItem) `slot:"addItem"`
+	_ func()              `slot:"clear"`
+}
+
+func (m *InstanceModel) init() {
+	m.SetRoles(map[int]*core.QByteArray{
+		InstancePID:       core.NewQByteArray2("pid", -1),
+		InstanceGameID:    core.NewQByteArray2("gameId", -1),
+		InstanceStartedAt: core.NewQByteArray2("startedAt", -1),
+		InstanceLabel:     core.NewQByteArray2("label", -1),
+	})
+
+	m.ConnectData(m.data)
+	m.ConnectRowCount(m.rowCount)
+	m.ConnectColumnCount(m.columnCount)
+	m.ConnectRoleNames(m.roleNames)
+	m.ConnectAddItem(m.addItem)
+	m.ConnectClear(m.clear)
+}
+
+func (m *InstanceModel) rowCount(*core.QModelIndex) int {
+	return len(m.Items())
+}
+
+func (m *InstanceModel) columnCount(*core.QModelIndex) int {
+	return 1
+}
+
+func (m *InstanceModel) roleNames() map[int]*core.QByteArray {
+	return m.Roles()
+}
+
+func (m *InstanceModel) data(index *core.QModelIndex, role int) *core.QVariant {
+	if !index.IsValid() {
+		return core.NewQVariant()
+	}
+
+	if index.Row() >= len(m.Items()) {
+		return core.NewQVariant()
+	}
+
+	item := m.Items()[index.Row()]
+
+	switch role {
+	case InstancePID:
+		return core.NewQVariant1(item.PID)
+	case InstanceGameID:
+		return core.NewQVariant1(item.GameID)
+	case InstanceStartedAt:
+		return core.NewQVariant1(item.StartedAt)
+	case InstanceLabel:
+		return core.NewQVariant1(item.Label)
+	default:
+		return core.NewQVariant()
+	}
+}
+
+// addItem adds an item to the model.
+func (m *InstanceModel) addItem(i *InstanceItem) {
+	m.BeginInsertRows(core.NewQModelIndex(), len(m.Items()), len(m.Items()))
+	m.SetItems(append(m.Items(), i))
+	m.EndInsertRows()
+}
+
+func (m *InstanceModel) clear() {
+	m.BeginResetModel()
+	m.SetItems([]*InstanceItem{})
+	m.EndResetModel()
+}
+
+func init() {
+	InstanceModel_QRegisterMetaType()
+	InstanceItem_QRegisterMetaType()
+}
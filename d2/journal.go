@@ -0,0 +1,217 @@
+package d2
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+const (
+	// journalName is the name of the journal file in the game directory.
+	journalName = ".slashdiablo-journal.json"
+
+	// backupDirName is the directory in the game directory where replaced files are kept.
+	backupDirName = ".slashdiablo-backup"
+)
+
+// journal records the files replaced while applying a patch step, so the whole
+// step can be rolled back if it fails midway or the launcher crashes.
+type journal struct {
+	path    string
+	Step    string         `json:"step"`
+	Entries []journalEntry `json:"entries"`
+}
+
+// journalEntry is a file that is about to be replaced.
+type journalEntry struct {
+	File    string `json:"file"`
+	Existed bool   `json:"existed"`
+}
+
+// beginJournal starts a journal for the patch step in the given game directory,
+// a journal left behind by a previous crash is rolled back first.
+func beginJournal(path string, step string) (*journal, error) {
+	if _, err := recoverJournal(path); err != nil {
+		return nil, err
+	}
+
+	j := &journal{
+		path:    path,
+		Step:    step,
+		Entries: make([]journalEntry, 0),
+	}
+
+	// The backup directory exists for as long as the journal does,
+	// so a journal without one is known to have been committed.
+	if err := os.MkdirAll(j.backupDir(), 0755); err != nil {
+		return nil, err
+	}
+
+	if err := j.persist(); err != nil {
+		return nil, err
+	}
+
+	return j, nil
+}
+
+// replace moves the downloaded .tmp file of the given file into place,
+// the file being replaced is backed up first.
+func (j *journal) replace(file string) error {
+	target := j.filePath(file)
+
+	_, err := os.Stat(target)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	existed := err == nil
+
+	// Record the operation before doing it, so a crash can always be recovered from.
+	j.Entries = append(j.Entries, journalEntry{File: file, Existed: existed})
+	if err := j.persist(); err != nil {
+		return err
+	}
+
+	if existed {
+		backup := j.backupPath(file)
+
+		if err := os.MkdirAll(filepath.Dir(backup), 0755); err != nil {
+			return err
+		}
+
+		if err := os.Rename(target, backup); err != nil {
+			return err
+		}
+	}
+
+	return os.Rename(target+".tmp", target)
+}
+
+// commit finishes the step, the backups aren't needed anymore.
+func (j *journal) commit() error {
+	// The journal has to go first, a crash before the backups are removed then leaves
+	// harmless backups behind. The other way around, the journal would be rolled back
+	// without its backups, removing the files the step installed.
+	if err := os.Remove(j.journalPath()); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return os.RemoveAll(j.backupDir())
+}
+
+// rollback restores every file replaced by the step, in reverse order.
+func (j *journal) rollback() error {
+	for i := len(j.Entries) - 1; i >= 0; i-- {
+		entry := j.Entries[i]
+		target := j.filePath(entry.File)
+
+		if entry.Existed {
+			backup := j.backupPath(entry.File)
+
+			_, err := os.Stat(backup)
+			if err != nil {
+				// The original was never moved, so it's still in place.
+				if os.IsNotExist(err) {
+					continue
+				}
+				return err
+			}
+
+			if err := os.Remove(target); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+
+			if err := os.Rename(backup, target); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		// The file didn't exist before the step, remove it.
+		if err := os.Remove(target); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	return j.commit()
+}
+
+// persist writes the journal to disk, replacing the previous one atomically.
+func (j *journal) persist() error {
+	body, err := json.Marshal(j)
+	if err != nil {
+		return err
+	}
+
+	journalPath := j.journalPath()
+
+	f, err := os.Create(journalPath + ".new")
+	if err != nil {
+		return err
+	}
+
+	if _, err := f.Write(body); err != nil {
+		f.Close()
+		return err
+	}
+
+	// Make sure the journal is on disk before touching any game files.
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(journalPath+".new", journalPath)
+}
+
+func (j *journal) journalPath() string {
+	return localizePath(fmt.Sprintf("%s/%s", j.path, journalName))
+}
+
+func (j *journal) backupDir() string {
+	return localizePath(fmt.Sprintf("%s/%s", j.path, backupDirName))
+}
+
+func (j *journal) filePath(file string) string {
+	return localizePath(fmt.Sprintf("%s/%s", j.path, file))
+}
+
+func (j *journal) backupPath(file string) string {
+	return localizePath(fmt.Sprintf("%s/%s/%s", j.path, backupDirName, file))
+}
+
+// recoverJournal rolls back the step recorded in the game directory, if any.
+// It returns true if an interrupted step was rolled back.
+func recoverJournal(path string) (bool, error) {
+	j := &journal{path: path}
+
+	body, err := ioutil.ReadFile(j.journalPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	if err := json.Unmarshal(body, j); err != nil {
+		return false, err
+	}
+
+	// The step was committed, but the journal wasn't removed.
+	if _, err := os.Stat(j.backupDir()); os.IsNotExist(err) {
+		return false, j.commit()
+	}
+
+	if err := j.rollback(); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
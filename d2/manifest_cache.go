@@ -0,0 +1,182 @@
+package d2
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/nokka/slashdiablo-launcher/clients/slashdiablo"
+	"github.com/nokka/slashdiablo-launcher/log"
+)
+
+const (
+	// manifestCacheDir is the directory in the config path where manifests are cached.
+	manifestCacheDir = "manifests"
+)
+
+// manifestCache keeps the last fetched manifests and other JSON files from the patch repository
+// on disk, they're only downloaded again if they've changed on the server, and used as is when
+// the server can't be reached.
+type manifestCache struct {
+	dir    string
+	client slashdiablo.Client
+	logger log.Logger
+	mux    sync.Mutex
+}
+
+// cachedFile is a file stored on disk with the validators it was fetched with.
+type cachedFile struct {
+	Validators slashdiablo.Validators `json:"validators"`
+	Contents   json.RawMessage        `json:"contents"`
+}
+
+// get decodes the JSON file at the given path in the patch repository into v.
+func (c *manifestCache) get(ctx context.Context, path string, v interface{}) error {
+	cached, err := c.load(path)
+	if err != nil {
+		// A broken cache entry is the same as no entry, we'll fetch it again.
+		c.logger.Error(err)
+	}
+
+	var validators slashdiablo.Validators
+	if cached != nil {
+		validators = cached.Validators
+	}
+
+	contents, validators, err := c.client.GetFileIfModified(ctx, path, validators)
+	if err != nil {
+		if cached == nil {
+			return err
+		}
+
+		if err == slashdiablo.ErrNotModified {
+			return json.Unmarshal(cached.Contents, v)
+		}
+
+		// The file has been removed or we've been cancelled, the cached copy won't help.
+		if slashdiablo.IsNotFound(err) || ctx.Err() != nil {
+			return err
+		}
+
+		c.logger.Info(fmt.Sprintf("using cached %s, couldn't reach the patch server: %s", path, err))
+		return json.Unmarshal(cached.Contents, v)
+	}
+
+	defer contents.Close()
+
+	bytes, err := ioutil.ReadAll(contents)
+	if err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal(bytes, v); err != nil {
+		return err
+	}
+
+	// Failing to cache shouldn't fail the patch, we'll just download it again next time.
+	if err := c.store(path, &cachedFile{Validators: validators, Contents: bytes}); err != nil {
+		c.logger.Error(err)
+	}
+
+	return nil
+}
+
+// manifestRun dedupes the files fetched during a single validation or patch, every file
+// is fetched at most once in a run however many games need it.
+type manifestRun struct {
+	cache *manifestCache
+	files map[string]json.RawMessage
+	mux   sync.Mutex
+}
+
+// newRun starts a run of fetches.
+func (c *manifestCache) newRun() *manifestRun {
+	return &manifestRun{
+		cache: c,
+		files: make(map[string]json.RawMessage),
+	}
+}
+
+// get decodes the JSON file at the given path in the patch repository into v,
+// the file is only fetched the first time it's asked for in the run.
+func (r *manifestRun) get(ctx context.Context, path string, v interface{}) error {
+	r.mux.Lock()
+	contents, ok := r.files[path]
+	r.mux.Unlock()
+
+	if !ok {
+		if err := r.cache.get(ctx, path, &contents); err != nil {
+			return err
+		}
+
+		r.mux.Lock()
+		r.files[path] = contents
+		r.mux.Unlock()
+	}
+
+	return json.Unmarshal(contents, v)
+}
+
+// load returns the cached file for the path, or nil if it hasn't been cached.
+func (c *manifestCache) load(path string) (*cachedFile, error) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	bytes, err := ioutil.ReadFile(c.filePath(path))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var cached cachedFile
+	if err := json.Unmarshal(bytes, &cached); err != nil {
+		return nil, err
+	}
+
+	return &cached, nil
+}
+
+// store writes the file to the cache, replacing the old one in a single rename
+// so a crash never leaves half a file behind.
+func (c *manifestCache) store(path string, cached *cachedFile) error {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	bytes, err := json.Marshal(cached)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(c.dir, 0755); err != nil {
+		return err
+	}
+
+	filePath := c.filePath(path)
+
+	if err := ioutil.WriteFile(filePath+".tmp", bytes, 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(filePath+".tmp", filePath)
+}
+
+// filePath returns where the file for the path is cached, 1.13c/manifest.json
+// is cached as 1.13c-manifest.json.
+func (c *manifestCache) filePath(path string) string {
+	return filepath.Join(c.dir, strings.Replace(path, "/", "-", -1))
+}
+
+func newManifestCache(configPath string, client slashdiablo.Client, logger log.Logger) *manifestCache {
+	return &manifestCache{
+		dir:    filepath.Join(configPath, manifestCacheDir),
+		client: client,
+		logger: logger,
+	}
+}
@@ -0,0 +1,68 @@
+package d2
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+const (
+	// defaultLaunchDelay is used for games without a launch delay, Diablo II won't
+	// start properly in multiple instances if they're started at the same time.
+	defaultLaunchDelay = 1500 * time.Millisecond
+)
+
+// ErrExitedBeforeReady is returned when an instance exits before it's ready.
+var ErrExitedBeforeReady = errors.New("game exited before it was ready")
+
+// ReadinessChecker decides when a started instance is ready, so the next instance can be started.
+type ReadinessChecker interface {
+	// Ready blocks until the instance is ready, the exited channel is closed if the instance exits.
+	Ready(ctx context.Context, instance Instance, exited <-chan struct{}) error
+}
+
+// aliveChecker considers an instance ready once it has been running for the duration.
+type aliveChecker struct {
+	duration time.Duration
+}
+
+// Ready will wait for the duration, unless the instance exits before that.
+func (c *aliveChecker) Ready(ctx context.Context, instance Instance, exited <-chan struct{}) error {
+	timer := time.NewTimer(c.duration)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-exited:
+		return ErrExitedBeforeReady
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// sleep waits for the duration, unless the context is cancelled before that.
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// launchDelay returns how long to wait between instances of a game with the configured delay.
+func launchDelay(milliseconds int) time.Duration {
+	if milliseconds <= 0 {
+		return defaultLaunchDelay
+	}
+
+	return time.Duration(milliseconds) * time.Millisecond
+}
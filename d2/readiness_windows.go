@@ -0,0 +1,94 @@
+// +build windows
+
+package d2
+
+import (
+	"context"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+const (
+	// windowPollInterval is how often to look for the game window.
+	windowPollInterval = 250 * time.Millisecond
+
+	// windowTimeout is how long to wait for the game window, before considering the game ready anyway.
+	windowTimeout = 30 * time.Second
+)
+
+// windowChecker considers an instance ready once it has shown its window,
+// and it has been running for the minimum duration.
+type windowChecker struct {
+	minimum time.Duration
+}
+
+// Ready will wait for the window of the instance, unless the instance exits before that.
+func (c *windowChecker) Ready(ctx context.Context, instance Instance, exited <-chan struct{}) error {
+	alive := &aliveChecker{duration: c.minimum}
+	if err := alive.Ready(ctx, instance, exited); err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(windowPollInterval)
+	defer ticker.Stop()
+
+	timeout := time.NewTimer(windowTimeout)
+	defer timeout.Stop()
+
+	for !hasVisibleWindow(instance.PID) {
+		select {
+		case <-ticker.C:
+		case <-timeout.C:
+			// Some setups never show a window we can find, don't block the launch on them.
+			return nil
+		case <-exited:
+			return ErrExitedBeforeReady
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	return nil
+}
+
+// windowSearch is passed to the enum windows callback.
+type windowSearch struct {
+	pid   uint32
+	found bool
+}
+
+// enumWindowsCallback is created once, Windows only allows a limited number of callbacks.
+var enumWindowsCallback = windows.NewCallback(func(hwnd windows.HWND, param unsafe.Pointer) uintptr {
+	search := (*windowSearch)(param)
+
+	var pid uint32
+	if _, err := windows.GetWindowThreadProcessId(hwnd, &pid); err != nil {
+		return 1
+	}
+
+	if pid == search.pid && windows.IsWindowVisible(hwnd) {
+		search.found = true
+
+		// Stop enumerating.
+		return 0
+	}
+
+	return 1
+})
+
+// hasVisibleWindow returns true if the process with the pid has a visible top level window.
+func hasVisibleWindow(pid int) bool {
+	search := &windowSearch{pid: uint32(pid)}
+
+	// Enumeration returns an error when it's stopped early, the search has the result.
+	windows.EnumWindows(enumWindowsCallback, unsafe.Pointer(search))
+
+	return search.found
+}
+
+// newReadinessChecker returns the readiness checker used on Windows.
+func newReadinessChecker(delay time.Duration) ReadinessChecker {
+	return &windowChecker{minimum: delay}
+}
@@ -0,0 +1,83 @@
+package d2
+
+// Patch components, named after their directory in the patch repository.
+const (
+	Component113c    = "1.13c"
+	ComponentCurrent = "current"
+	ComponentMaphack = "maphack"
+	ComponentHD      = "hd"
+)
+
+const (
+	// version113c is the Diablo II version required to play on Slashdiablo.
+	version113c = "1.13c"
+)
+
+// ValidationReport describes how the configured games differ from the current patches.
+type ValidationReport struct {
+	Games []GameReport
+}
+
+// UpToDate returns true if none of the games need to be patched.
+func (r *ValidationReport) UpToDate() bool {
+	for _, g := range r.Games {
+		if !g.UpToDate() {
+			return false
+		}
+	}
+
+	return true
+}
+
+// GameReport describes how a game install differs from the current patches.
+type GameReport struct {
+	GameID   string
+	Location string
+
+	// Version is the detected Game.exe version, empty if it's unknown.
+	Version string
+
+	// Components are the patch components the game should have.
+	Components []ComponentReport
+
+	// UnexpectedFiles are mod files left in the install, for mods that are disabled.
+	UnexpectedFiles []string
+}
+
+// UpToDate returns true if the game doesn't need to be patched.
+func (g *GameReport) UpToDate() bool {
+	if g.Version != version113c || len(g.UnexpectedFiles) > 0 {
+		return false
+	}
+
+	for _, c := range g.Components {
+		if !c.UpToDate() {
+			return false
+		}
+	}
+
+	return true
+}
+
+// BytesToDownload returns the number of bytes needed to patch the game.
+func (g *GameReport) BytesToDownload() int64 {
+	var total int64
+	for _, c := range g.Components {
+		total += c.Bytes
+	}
+
+	return total
+}
+
+// ComponentReport describes the state of a patch component in a game install.
+type ComponentReport struct {
+	Component       string
+	MissingFiles    []string
+	MismatchedFiles []string
+	Bytes           int64
+}
+
+// UpToDate returns true if the component doesn't need to be patched.
+func (c *ComponentReport) UpToDate() bool {
+	return len(c.MissingFiles) == 0 && len(c.MismatchedFiles) == 0
+}
@@ -0,0 +1,122 @@
+package d2
+
+import (
+	"os/exec"
+	"time"
+
+	"github.com/nokka/slashdiablo-launcher/storage"
+)
+
+const (
+	// defaultMaxRestarts is how many times in a row an instance is restarted, unless configured.
+	defaultMaxRestarts = 3
+
+	// restartBackoff is how long to wait before the first restart, doubled for every restart in a row.
+	restartBackoff = 2 * time.Second
+
+	// maxRestartBackoff is the longest to wait before restarting.
+	maxRestartBackoff = 1 * time.Minute
+
+	// restartResetAfter is how long an instance has to run for the restarts
+	// to no longer count as in a row.
+	restartResetAfter = 2 * time.Minute
+
+	// maxCrashHistory is how many crashes are kept in the history.
+	maxCrashHistory = 50
+)
+
+// Crash is an instance that exited unexpectedly.
+type Crash struct {
+	GameID    string
+	Label     string
+	PID       int
+	StartedAt time.Time
+	ExitedAt  time.Time
+	ExitCode  int
+	Stderr    string
+
+	// Set if the instance was restarted after the crash.
+	Restarted bool
+}
+
+// restartPolicy is when instances of a game are restarted after exiting.
+type restartPolicy struct {
+	mode        string
+	maxRestarts int
+}
+
+// restarts reports if the policy restarts instances at all.
+func (r restartPolicy) restarts() bool {
+	return r.mode == storage.RestartOnCrash || r.mode == storage.RestartAlways
+}
+
+// shouldRestart reports if an instance exiting after the given number of restarts in a row
+// should be restarted again.
+func (r restartPolicy) shouldRestart(crashed bool, restarts int) bool {
+	if restarts >= r.maxRestarts {
+		return false
+	}
+
+	switch r.mode {
+	case storage.RestartAlways:
+		return true
+	case storage.RestartOnCrash:
+		return crashed
+	default:
+		return false
+	}
+}
+
+// newRestartPolicy returns the restart policy of the game, games without one are never restarted.
+func newRestartPolicy(game storage.Game) (restartPolicy, error) {
+	if err := game.ValidateRestartPolicy(); err != nil {
+		return restartPolicy{}, err
+	}
+
+	mode := game.RestartPolicy
+	if mode == "" {
+		mode = storage.RestartNever
+	}
+
+	maxRestarts := game.MaxRestarts
+	if maxRestarts <= 0 {
+		maxRestarts = defaultMaxRestarts
+	}
+
+	return restartPolicy{
+		mode:        mode,
+		maxRestarts: maxRestarts,
+	}, nil
+}
+
+// command creates the commands starting instances of a game, and knows when they're restarted.
+type command struct {
+	gameID string
+	slot   int
+	label  string
+	create func() (*exec.Cmd, error)
+	policy restartPolicy
+
+	// checker decides when a started instance is ready, delay is the launch delay of the game.
+	checker ReadinessChecker
+	delay   time.Duration
+}
+
+// pendingRestart is an instance waiting to be restarted, until cancelled.
+type pendingRestart struct {
+	instance Instance
+	cancel   chan struct{}
+}
+
+// backoff returns how long to wait before the restart with the given number in a row.
+func backoff(restart int) time.Duration {
+	delay := restartBackoff
+	for i := 1; i < restart; i++ {
+		delay *= 2
+		if delay >= maxRestartBackoff {
+			return maxRestartBackoff
+		}
+	}
+
+	return delay
+}
@@ -0,0 +1,54 @@
+package d2
+
+import (
+	"io/ioutil"
+	"os"
+)
+
+const (
+	// resumeSuffix is appended to the .tmp file path to store what the partial download contains.
+	resumeSuffix = ".resume"
+)
+
+// resumeOffset returns the number of bytes already downloaded into the tmp file
+// for the given source, if the tmp file holds something else we start over.
+func resumeOffset(tmpPath string, source string) (int64, error) {
+	stored, err := ioutil.ReadFile(tmpPath + resumeSuffix)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, err
+	}
+
+	// The partial download belongs to another file or version.
+	if string(stored) != source {
+		return 0, nil
+	}
+
+	info, err := os.Stat(tmpPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, err
+	}
+
+	return info.Size(), nil
+}
+
+// markResumable stores the source of the tmp file next to it, so an interrupted
+// download can be resumed on the next patch.
+func markResumable(tmpPath string, source string) error {
+	return ioutil.WriteFile(tmpPath+resumeSuffix, []byte(source), 0644)
+}
+
+// clearResumable removes the stored source of the tmp file.
+func clearResumable(tmpPath string) error {
+	err := os.Remove(tmpPath + resumeSuffix)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return nil
+}
@@ -1,13 +1,11 @@
 package d2
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
 	"io"
-	"io/ioutil"
 	"os"
-	"strings"
-	"sync"
+	"os/exec"
 	"time"
 
 	"github.com/nokka/slashdiablo-launcher/clients/slashdiablo"
@@ -18,20 +16,42 @@ import (
 
 // Service is responsible for all things related to the Slashdiablo ladder.
 type Service interface {
-	// Exec is responsible for executing the Diablo II game.
-	Exec() error
+	// Exec is responsible for executing the Diablo II games, reporting the state of
+	// every instance on the returned channel. The channel is closed once all have launched.
+	Exec(ctx context.Context) <-chan LaunchState
 
-	// ValidateGameVersions will make sure the game is up to date with expected patch.
-	ValidateGameVersions() (bool, error)
+	// ValidateGameVersions will report how the games differ from the expected patch.
+	ValidateGameVersions(ctx context.Context) (*ValidationReport, error)
 
-	// Patch will patch Diablo II to the correct version.
-	Patch(done chan bool) (<-chan float32, <-chan PatchState)
+	// Patch will patch Diablo II to the correct version, until the context is cancelled.
+	Patch(ctx context.Context, done chan bool) (<-chan float32, <-chan PatchState)
 
 	// ApplyDEP will apply Windows specific fix for DEP.
 	ApplyDEP(path string) error
 
 	// SetGateway is responsible for setting Battle.net gateway.
 	SetGateway(gateway string) error
+
+	// RecoverPatches will roll back patches that were interrupted by a crash.
+	RecoverPatches() error
+
+	// Instances returns the running game instances.
+	Instances() []Instance
+
+	// WatchInstances returns a channel receiving the running game instances every time they change.
+	WatchInstances() <-chan []Instance
+
+	// StopInstance will stop the running game instance with the pid.
+	StopInstance(pid int) error
+
+	// StopGame will stop all running instances of the game.
+	StopGame(gameID string) error
+
+	// StopAll will stop all running game instances.
+	StopAll() error
+
+	// Crashes returns the game instances that exited unexpectedly, the latest crash last.
+	Crashes() []Crash
 }
 
 // Service is responsible for all things related to Diablo II.
@@ -39,160 +59,305 @@ type service struct {
 	slashdiabloClient slashdiablo.Client
 	configService     config.Service
 	logger            log.Logger
-	gameStates        chan execState
-	runningGames      []game
-	mux               sync.Mutex
+	manifests         *manifestCache
+	versions          *versionDetector
+	supervisor        *supervisor
 }
 
-type game struct {
-	PID    int
-	GameID string
-}
+// Exec will exec Diablo 2 installs, every instance is ready before the next one is started.
+func (s *service) Exec(ctx context.Context) <-chan LaunchState {
+	state := make(chan LaunchState)
 
-type execState struct {
-	pid *int
-	err error
+	go func() {
+		defer close(state)
+
+		if err := s.exec(ctx, state); err != nil {
+			select {
+			case state <- LaunchState{Status: LaunchFailed, Error: err}:
+			case <-ctx.Done():
+			}
+		}
+	}()
+
+	return state
 }
 
-// Exec will exec Diablo 2 installs.
-func (s *service) Exec() error {
+func (s *service) exec(ctx context.Context, state chan LaunchState) error {
 	conf, err := s.configService.Read()
 	if err != nil {
 		return err
 	}
 
-	// Mutate the number of instances to launch to take into
-	// account the number of games already running.
-	s.mutateInstancesToLaunch(conf.Games)
+	// Only used where the game can't be run natively.
+	wine := newWineConfig(conf)
 
+	// Check every policy up front, so a broken game config doesn't leave the games half launched.
+	policies := make(map[string]restartPolicy, len(conf.Games))
 	for _, g := range conf.Games {
+		policy, err := newRestartPolicy(g)
+		if err != nil {
+			return err
+		}
+
+		policies[g.ID] = policy
+	}
+
+	for _, g := range conf.Games {
+		// Diablo won't start properly in multiple instances, unless the previous one is ready.
+		delay := launchDelay(g.LaunchDelay)
+		checker := newReadinessChecker(delay)
+
 		for i := 0; i < g.Instances; i++ {
-			// Stall between each exec, otherwise Diablo won't start properly in multiple instances.
-			time.Sleep(1500 * time.Millisecond)
+			// Instances that are already running aren't launched again.
+			if s.supervisor.isRunning(g.ID, i) {
+				continue
+			}
+
+			profile := instanceProfile(g, i)
+			location := g.Location
+
+			// The command is used again when the instance is restarted.
+			c := &command{
+				gameID: g.ID,
+				slot:   i,
+				label:  profile.Label,
+				create: func() (*exec.Cmd, error) {
+					return gameCommand(location, profile.Flags, wine)
+				},
+				policy:  policies[g.ID],
+				checker: checker,
+				delay:   delay,
+			}
+
+			current := LaunchState{
+				GameID:    g.ID,
+				Location:  g.Location,
+				Label:     profile.Label,
+				Instance:  i + 1,
+				Instances: g.Instances,
+				Status:    LaunchStarting,
+			}
 
-			// The third argument is a channel, listened on by listenForGameStates().
-			pid, err := launch(g.Location, g.Flags, s.gameStates)
+			sendLaunchState(ctx, state, current)
+
+			// The supervisor keeps track of the game until it exits, and restarts it by the policy.
+			err := s.supervisor.launch(ctx, c, func(instance Instance) {
+				current.PID = instance.PID
+				current.Status = LaunchWaiting
+				sendLaunchState(ctx, state, current)
+			})
 			if err != nil {
 				return err
 			}
 
-			// Add the started game to our slice of games.
-			s.runningGames = append(s.runningGames, game{PID: *pid, GameID: g.ID})
+			current.Status = LaunchReady
+			sendLaunchState(ctx, state, current)
 		}
 	}
 
 	return nil
 }
 
-// ValidateGameVersions will check if the games are up to date.
-func (s *service) ValidateGameVersions() (bool, error) {
+// instanceProfile returns the profile of the instance in the given slot of the game,
+// instances without a profile of their own use the flags of the game.
+func instanceProfile(game storage.Game, slot int) storage.InstanceProfile {
+	var profile storage.InstanceProfile
+	if slot < len(game.Profiles) {
+		profile = game.Profiles[slot]
+	}
+
+	if profile.Flags == nil {
+		profile.Flags = game.Flags
+	}
+
+	return profile
+}
+
+// ValidateGameVersions will check if the games are up to date, and report what differs.
+func (s *service) ValidateGameVersions(ctx context.Context) (*ValidationReport, error) {
 	conf, err := s.configService.Read()
 	if err != nil {
-		return false, err
+		return nil, err
+	}
+
+	// Every file is fetched once for the whole validation.
+	run := s.manifests.newRun()
+
+	// Get the 1.13c patch, used for games that aren't 1.13c.
+	manifest113c, err := s.getManifest(ctx, run, "1.13c/manifest.json")
+	if err != nil {
+		return nil, err
 	}
 
 	// Get current slash patch and compare.
-	slashManifest, err := s.getManifest("current/manifest.json")
+	slashManifest, err := s.getManifest(ctx, run, "current/manifest.json")
 	if err != nil {
-		return false, err
+		return nil, err
 	}
 
 	// Get current maphack patch and compare.
-	maphackManifest, err := s.getManifest("maphack/manifest.json")
+	maphackManifest, err := s.getManifest(ctx, run, "maphack/manifest.json")
 	if err != nil {
-		return false, err
+		return nil, err
 	}
 
 	// Get current HD patch and compare.
-	HDManifest, err := s.getManifest("hd/manifest.json")
+	HDManifest, err := s.getManifest(ctx, run, "hd/manifest.json")
 	if err != nil {
-		return false, err
+		return nil, err
 	}
 
-	upToDate := true
+	// Learn about versions released after this build, if the server knows any.
+	if err := s.updateVersions(ctx, run); err != nil {
+		return nil, err
+	}
 
-	if len(conf.Games) > 0 {
-		for _, game := range conf.Games {
-			valid, err := validate113cVersion(game.Location)
-			if err != nil {
-				return false, err
-			}
+	report := &ValidationReport{
+		Games: make([]GameReport, 0, len(conf.Games)),
+	}
 
-			// Game wasn't 1.13c, needs to be updated.
-			if !valid {
-				return false, nil
-			}
+	for _, game := range conf.Games {
+		version, err := s.versions.gameVersion(game.Location)
+		if err != nil {
+			return nil, err
+		}
+
+		gameReport := GameReport{
+			GameID:   game.ID,
+			Location: game.Location,
+			Version:  version,
+		}
 
-			// Check if the current game install is up to date with the slash patch.
-			slashFiles, _, err := s.getFilesToPatch(slashManifest.Files, game.Location, nil)
+		// Game wasn't 1.13c, report the files needed to update it.
+		if version != version113c {
+			component, err := s.compareComponent(Component113c, manifest113c.Files, game.Location, nil)
 			if err != nil {
-				return false, err
+				return nil, err
 			}
 
-			// Slash patch isn't up to date.
-			if len(slashFiles) > 0 {
-				return false, nil
-			}
+			gameReport.Components = append(gameReport.Components, *component)
+		}
 
-			// If the user has chosen to override the maphack config with their own,
-			// we need to make sure the config is being ignored from the patch.
-			var ignoredMaphackFiles []string
+		// Check if the current game install is up to date with the slash patch.
+		component, err := s.compareComponent(ComponentCurrent, slashManifest.Files, game.Location, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		gameReport.Components = append(gameReport.Components, *component)
+
+		// If the user has chosen to override the maphack config with their own,
+		// we need to make sure the config is being ignored from the patch.
+		var ignoredMaphackFiles []string
+
+		if game.OverrideBHCfg {
+			ignoredMaphackFiles = append(ignoredMaphackFiles, "BH.cfg")
+		}
 
-			if game.OverrideBHCfg {
-				ignoredMaphackFiles = append(ignoredMaphackFiles, "BH.cfg")
+		// Maphack is enabled, make sure there's no missing files.
+		if game.Maphack {
+			component, err := s.compareComponent(ComponentMaphack, maphackManifest.Files, game.Location, ignoredMaphackFiles)
+			if err != nil {
+				return nil, err
 			}
 
-			// Check how many files aren't up to date with maphack.
-			missingMaphackFiles, _, err := s.getFilesToPatch(maphackManifest.Files, game.Location, ignoredMaphackFiles)
+			gameReport.Components = append(gameReport.Components, *component)
+		} else {
+			installed, err := isMaphackInstalled(game.Location)
 			if err != nil {
-				return false, err
+				return nil, err
 			}
 
-			// Maphack is enabled, make sure there's no missing files.
-			if game.Maphack {
-				// Maphack patch isn't up to date.
-				if len(missingMaphackFiles) > 0 {
-					return false, nil
-				}
-			} else {
-				installed, err := isMaphackInstalled(game.Location)
+			// Maphack wasn't supposed to be installed, but it is, we need to update.
+			if installed {
+				leftovers, err := s.getInstalledFiles(maphackManifest.Files, game.Location, ignoredMaphackFiles)
 				if err != nil {
-					return false, err
+					return nil, err
 				}
 
-				// Maphack wasn't supposed to be installed, but it is, we need to update.
-				if installed {
-					return false, nil
-				}
+				gameReport.UnexpectedFiles = append(gameReport.UnexpectedFiles, leftovers...)
 			}
+		}
 
-			// Check if the current game install is up to date with the HD patch.
-			missingHDFiles, _, err := s.getFilesToPatch(HDManifest.Files, game.Location, nil)
+		if game.HD {
+			component, err := s.compareComponent(ComponentHD, HDManifest.Files, game.Location, nil)
 			if err != nil {
-				return false, err
+				return nil, err
 			}
 
-			if game.HD {
-				// HD patch isn't up to date.
-				if len(missingHDFiles) > 0 {
-					return false, nil
-				}
-			} else {
-				installed, err := isHDInstalled(game.Location)
+			gameReport.Components = append(gameReport.Components, *component)
+		} else {
+			installed, err := isHDInstalled(game.Location)
+			if err != nil {
+				return nil, err
+			}
+
+			// HD wasn't supposed to be installed, but it is, we need to update.
+			if installed {
+				leftovers, err := s.getInstalledFiles(HDManifest.Files, game.Location, nil)
 				if err != nil {
-					return false, err
+					return nil, err
 				}
 
-				// HD wasn't supposed to be installed, but it is, we need to update.
-				if installed {
-					return false, nil
-				}
+				gameReport.UnexpectedFiles = append(gameReport.UnexpectedFiles, leftovers...)
+			}
+		}
+
+		report.Games = append(report.Games, gameReport)
+	}
+
+	return report, nil
+}
+
+// compareComponent reports how the files of the component differ from the install.
+func (s *service) compareComponent(name string, files []PatchFile, path string, filesToIgnore []string) (*ComponentReport, error) {
+	missing, mismatched, err := s.compareFiles(files, path, filesToIgnore)
+	if err != nil {
+		return nil, err
+	}
+
+	component := &ComponentReport{
+		Component:       name,
+		MissingFiles:    make([]string, 0, len(missing)),
+		MismatchedFiles: make([]string, 0, len(mismatched)),
+	}
+
+	for _, f := range missing {
+		component.MissingFiles = append(component.MissingFiles, f.Name)
+		component.Bytes += f.ContentLength
+	}
+
+	for _, f := range mismatched {
+		component.MismatchedFiles = append(component.MismatchedFiles, f.Name)
+		component.Bytes += f.ContentLength
+	}
+
+	return component, nil
+}
+
+// getInstalledFiles returns the names of the patch files that exist in the install.
+func (s *service) getInstalledFiles(files []PatchFile, path string, filesToIgnore []string) ([]string, error) {
+	installed := make([]string, 0)
+
+	for _, f := range files {
+		if isIgnored(f.Name, filesToIgnore) {
+			continue
+		}
+
+		_, err := os.Stat(localizePath(fmt.Sprintf("%s/%s", path, f.Name)))
+		if err != nil {
+			// File didn't exist on disk, continue to next.
+			if os.IsNotExist(err) {
+				continue
 			}
+			// Unknown error.
+			return nil, err
 		}
+
+		installed = append(installed, f.Name)
 	}
 
-	// Games are both 1.13c and up to date with Slash patch, maphack and HD.
-	return upToDate, nil
+	return installed, nil
 }
 
 func (s *service) resetPatch(path string, files []PatchFile, filesToIgnore []string) error {
@@ -244,116 +409,137 @@ func (s *service) resetPatch(path string, files []PatchFile, filesToIgnore []str
 }
 
 // Patch will check for updates and if found, patch the game, both D2 and HD version.
-func (s *service) Patch(done chan bool) (<-chan float32, <-chan PatchState) {
+// Cancelling the context stops the patch, both channels are closed once patching stops.
+func (s *service) Patch(ctx context.Context, done chan bool) (<-chan float32, <-chan PatchState) {
 	progress := make(chan float32)
 	state := make(chan PatchState)
 
 	go func() {
-		conf, err := s.configService.Read()
-		if err != nil {
-			state <- PatchState{Error: err}
-			return
-		}
+		defer close(progress)
+		defer close(state)
 
-		// Download maphack manifest from patch repository, , we'll use it multiple times.
-		maphackManifest, err := s.getManifest("maphack/manifest.json")
-		if err != nil {
-			state <- PatchState{Error: err}
+		if err := s.patch(ctx, state, progress); err != nil {
+			// The patch was cancelled, nobody is waiting for an error.
+			if ctx.Err() != nil {
+				return
+			}
+
+			sendState(ctx, state, newPatchErrorState(err))
 			return
 		}
-		// Download HD manifest from patch repository, we'll use it multiple times.
-		hdManifest, err := s.getManifest("hd/manifest.json")
-		if err != nil {
-			state <- PatchState{Error: err}
-			return
+
+		select {
+		case done <- true:
+		case <-ctx.Done():
 		}
+	}()
 
-		for _, game := range conf.Games {
-			// If the user has chosen to override the maphack config with their own,
-			// we need to make sure the config is being ignored from the patch, and also
-			// when reseting the maphack patch.
-			var ignoredMaphackFiles []string
+	return progress, state
+}
 
-			if game.OverrideBHCfg {
-				ignoredMaphackFiles = append(ignoredMaphackFiles, "BH.cfg")
-			}
+func (s *service) patch(ctx context.Context, state chan PatchState, progress chan float32) error {
+	conf, err := s.configService.Read()
+	if err != nil {
+		return err
+	}
 
-			// If maphack is disabled, make sure no rogue files have managed to stay in the directory.
-			if !game.Maphack {
-				installed, err := isMaphackInstalled(game.Location)
-				if err != nil {
-					state <- PatchState{Error: err}
-					return
-				}
+	// Download all manifests from patch repository up front, we'll use them for every game.
+	run := s.manifests.newRun()
 
-				// If maphack is installed, but was supposed to be disabled, reset the patch.
-				if installed {
-					err := s.resetPatch(game.Location, maphackManifest.Files, ignoredMaphackFiles)
-					if err != nil {
-						state <- PatchState{Error: err}
-						return
-					}
-				}
-			}
+	manifest113c, err := s.getManifest(ctx, run, "1.13c/manifest.json")
+	if err != nil {
+		return err
+	}
 
-			// If HD is disabled, make sure no rogue files have managed to stay in the directory.
-			if !game.HD {
-				installed, err := isHDInstalled(game.Location)
-				if err != nil {
-					state <- PatchState{Error: err}
-					return
-				}
+	slashManifest, err := s.getManifest(ctx, run, "current/manifest.json")
+	if err != nil {
+		return err
+	}
 
-				// If HD is installed, but was supposed to be disabled, reset the patch.
-				if installed {
-					err := s.resetPatch(game.Location, hdManifest.Files, nil)
-					if err != nil {
-						state <- PatchState{Error: err}
-						return
-					}
-				}
-			}
+	maphackManifest, err := s.getManifest(ctx, run, "maphack/manifest.json")
+	if err != nil {
+		return err
+	}
 
-			// The install has been reset, let's validate the 1.13c version and apply missing files.
-			if err := s.apply113c(game.Location, state, progress); err != nil {
-				state <- PatchState{Error: err}
-				return
-			}
+	hdManifest, err := s.getManifest(ctx, run, "hd/manifest.json")
+	if err != nil {
+		return err
+	}
+
+	for _, game := range conf.Games {
+		// If the user has chosen to override the maphack config with their own,
+		// we need to make sure the config is being ignored from the patch, and also
+		// when reseting the maphack patch.
+		var ignoredMaphackFiles []string
+
+		if game.OverrideBHCfg {
+			ignoredMaphackFiles = append(ignoredMaphackFiles, "BH.cfg")
+		}
 
-			err = s.applySlashPatch(game.Location, state, progress)
+		// If maphack is disabled, make sure no rogue files have managed to stay in the directory.
+		if !game.Maphack {
+			installed, err := isMaphackInstalled(game.Location)
 			if err != nil {
-				state <- PatchState{Error: err}
-				return
+				return err
 			}
 
-			if game.Maphack {
-				err = s.applyMaphack(game.Location, state, progress, maphackManifest.Files, ignoredMaphackFiles)
+			// If maphack is installed, but was supposed to be disabled, reset the patch.
+			if installed {
+				err := s.resetPatch(game.Location, maphackManifest.Files, ignoredMaphackFiles)
 				if err != nil {
-					state <- PatchState{Error: err}
-					return
+					return err
 				}
 			}
+		}
 
-			if game.HD {
-				err = s.applyHDMod(game.Location, state, progress, hdManifest.Files)
+		// If HD is disabled, make sure no rogue files have managed to stay in the directory.
+		if !game.HD {
+			installed, err := isHDInstalled(game.Location)
+			if err != nil {
+				return err
+			}
+
+			// If HD is installed, but was supposed to be disabled, reset the patch.
+			if installed {
+				err := s.resetPatch(game.Location, hdManifest.Files, nil)
 				if err != nil {
-					state <- PatchState{Error: err}
-					return
+					return err
 				}
 			}
+		}
 
-			// Finally set os specific configurations, such as compatibility mode.
-			err := configureForOS(game.Location)
+		// The install has been reset, let's validate the 1.13c version and apply missing files.
+		if err := s.apply113c(ctx, game.Location, state, progress, manifest113c.Files); err != nil {
+			return err
+		}
+
+		err = s.applySlashPatch(ctx, game.Location, state, progress, slashManifest.Files)
+		if err != nil {
+			return err
+		}
+
+		if game.Maphack {
+			err = s.applyMaphack(ctx, game.Location, state, progress, maphackManifest.Files, ignoredMaphackFiles)
 			if err != nil {
-				state <- PatchState{Error: err}
-				return
+				return err
 			}
 		}
 
-		done <- true
-	}()
+		if game.HD {
+			err = s.applyHDMod(ctx, game.Location, state, progress, hdManifest.Files)
+			if err != nil {
+				return err
+			}
+		}
 
-	return progress, state
+		// Finally set os specific configurations, such as compatibility mode.
+		err := configureForOS(game.Location)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
 
 // ApplyDEP will run  data execution prevention (DEP) on the Game.exe in the path.
@@ -364,8 +550,18 @@ func (s *service) ApplyDEP(path string) error {
 
 // SetGateway will set the given gateway for the user.
 func (s *service) SetGateway(gateway string) error {
+	conf, err := s.configService.Read()
+	if err != nil {
+		return err
+	}
+
+	gatewayHex, bnetIP, err := gatewayValues(gateway, conf.Gateways)
+	if err != nil {
+		return err
+	}
+
 	// Set gateway in the OS specific way.
-	err := setGateway(gateway)
+	err = setGateway(gatewayHex, bnetIP, newWineConfig(conf))
 	if err != nil {
 		return err
 	}
@@ -379,68 +575,72 @@ func (s *service) SetGateway(gateway string) error {
 	return nil
 }
 
-func (s *service) mutateInstancesToLaunch(games []storage.Game) {
-	for i := 0; i < len(games); i++ {
-		var runningCount int
-		for _, running := range s.runningGames {
-			if games[i].ID == running.GameID {
-				runningCount++
-			}
+// RecoverPatches will roll back any patch step that was interrupted, leaving
+// the game files the way they were before the step started.
+func (s *service) RecoverPatches() error {
+	conf, err := s.configService.Read()
+	if err != nil {
+		return err
+	}
+
+	for _, game := range conf.Games {
+		recovered, err := recoverJournal(game.Location)
+		if err != nil {
+			return err
 		}
 
-		// If any games of this id is running already, subtract the number
-		// and mutate the game so the next time we launch, we launch the correct number.
-		games[i].Instances = games[i].Instances - runningCount
+		if recovered {
+			s.logger.Info(fmt.Sprintf("rolled back interrupted patch in %s", game.Location))
+		}
 	}
+
+	return nil
 }
 
-func (s *service) listenForGameStates() {
-	for {
-		select {
-		case state := <-s.gameStates:
-			// Something went wrong while execing, log error.
-			if state.err != nil {
-				s.logger.Error(fmt.Errorf("Diablo II exec with code: %s", state.err))
-			}
+// Instances returns the running game instances.
+func (s *service) Instances() []Instance {
+	return s.supervisor.list()
+}
 
-			s.mux.Lock()
+// WatchInstances returns a channel receiving the running game instances every time they change.
+func (s *service) WatchInstances() <-chan []Instance {
+	return s.supervisor.watch()
+}
 
-			// Game exited, remove it from the slice based on pid.
-			for index, g := range s.runningGames {
-				if state.pid != nil && g.PID == *state.pid {
-					s.runningGames = append(s.runningGames[:index], s.runningGames[index+1:]...)
-				}
-			}
+// StopInstance will stop the running game instance with the pid, the instance
+// is asked to exit and killed if it hasn't exited in time.
+func (s *service) StopInstance(pid int) error {
+	return s.supervisor.stop(pid)
+}
 
-			s.mux.Unlock()
-		}
-	}
+// StopGame will stop all running instances of the game.
+func (s *service) StopGame(gameID string) error {
+	return s.supervisor.stopGame(gameID)
 }
 
-func (s *service) apply113c(path string, state chan PatchState, progress chan float32) error {
-	state <- PatchState{Message: "Checking game version..."}
+// StopAll will stop all running game instances.
+func (s *service) StopAll() error {
+	return s.supervisor.stopAll()
+}
 
-	// Download manifest from patch repository.
-	manifest, err := s.getManifest("1.13c/manifest.json")
-	if err != nil {
-		return err
-	}
+// Crashes returns the game instances that exited unexpectedly, the latest crash last.
+func (s *service) Crashes() []Crash {
+	return s.supervisor.crashHistory()
+}
+
+func (s *service) apply113c(ctx context.Context, path string, state chan PatchState, progress chan float32, manifestFiles []PatchFile) error {
+	sendState(ctx, state, PatchState{Message: "Checking game version..."})
 
 	// Figure out which files to patch.
-	patchFiles, patchLength, err := s.getFilesToPatch(manifest.Files, path, nil)
+	patchFiles, patchLength, err := s.getFilesToPatch(manifestFiles, path, nil)
 	if err != nil {
 		return err
 	}
 
 	if len(patchFiles) > 0 {
-		state <- PatchState{Message: fmt.Sprintf("Updating %s to 1.13c", path)}
-		if err := s.doPatch(patchFiles, patchLength, "1.13c", path, progress); err != nil {
-			patchErr := err
-			// Make sure we clean up the failed patch.
-			if err := s.cleanUpFailedPatch(path); err != nil {
-				return fmt.Errorf("Clean up error: %s : %s", patchErr, err)
-			}
-
+		sendState(ctx, state, PatchState{Message: fmt.Sprintf("Updating %s to 1.13c", path)})
+		if err := s.doPatch(ctx, patchFiles, patchLength, "1.13c", path, progress); err != nil {
+			// Downloaded .tmp files are kept, so the patch can be resumed.
 			return err
 		}
 	}
@@ -448,31 +648,20 @@ func (s *service) apply113c(path string, state chan PatchState, progress chan fl
 	return nil
 }
 
-func (s *service) applySlashPatch(path string, state chan PatchState, progress chan float32) error {
-	state <- PatchState{Message: "Checking Slashdiablo patch..."}
-
-	// Download manifest from patch repository.
-	manifest, err := s.getManifest("current/manifest.json")
-	if err != nil {
-		return err
-	}
+func (s *service) applySlashPatch(ctx context.Context, path string, state chan PatchState, progress chan float32, manifestFiles []PatchFile) error {
+	sendState(ctx, state, PatchState{Message: "Checking Slashdiablo patch..."})
 
 	// Figure out which files to patch.
-	patchFiles, patchLength, err := s.getFilesToPatch(manifest.Files, path, nil)
+	patchFiles, patchLength, err := s.getFilesToPatch(manifestFiles, path, nil)
 	if err != nil {
 		return err
 	}
 
 	if len(patchFiles) > 0 {
-		state <- PatchState{Message: fmt.Sprintf("Updating %s to current Slashdiablo patch", path)}
-
-		if err = s.doPatch(patchFiles, patchLength, "current", path, progress); err != nil {
-			patchErr := err
-			// Make sure we clean up the failed patch.
-			if err := s.cleanUpFailedPatch(path); err != nil {
-				return fmt.Errorf("Clean up error: %s : %s", patchErr, err)
-			}
+		sendState(ctx, state, PatchState{Message: fmt.Sprintf("Updating %s to current Slashdiablo patch", path)})
 
+		if err = s.doPatch(ctx, patchFiles, patchLength, "current", path, progress); err != nil {
+			// Downloaded .tmp files are kept, so the patch can be resumed.
 			return err
 		}
 	}
@@ -480,8 +669,8 @@ func (s *service) applySlashPatch(path string, state chan PatchState, progress c
 	return nil
 }
 
-func (s *service) applyMaphack(path string, state chan PatchState, progress chan float32, manifestFiles []PatchFile, ignoredFiles []string) error {
-	state <- PatchState{Message: "Checking maphack..."}
+func (s *service) applyMaphack(ctx context.Context, path string, state chan PatchState, progress chan float32, manifestFiles []PatchFile, ignoredFiles []string) error {
+	sendState(ctx, state, PatchState{Message: "Checking maphack..."})
 
 	// Figure out which files to patch.
 	patchFiles, patchLength, err := s.getFilesToPatch(manifestFiles, path, ignoredFiles)
@@ -490,14 +679,9 @@ func (s *service) applyMaphack(path string, state chan PatchState, progress chan
 	}
 
 	if len(patchFiles) > 0 {
-		state <- PatchState{Message: fmt.Sprintf("Updating %s to latest maphack version", path)}
-		if err = s.doPatch(patchFiles, patchLength, "maphack", path, progress); err != nil {
-			patchErr := err
-			// Make sure we clean up the failed patch.
-			if err := s.cleanUpFailedPatch(path); err != nil {
-				return fmt.Errorf("Clean up error: %s : %s", patchErr, err)
-			}
-
+		sendState(ctx, state, PatchState{Message: fmt.Sprintf("Updating %s to latest maphack version", path)})
+		if err = s.doPatch(ctx, patchFiles, patchLength, "maphack", path, progress); err != nil {
+			// Downloaded .tmp files are kept, so the patch can be resumed.
 			return err
 		}
 	}
@@ -505,9 +689,9 @@ func (s *service) applyMaphack(path string, state chan PatchState, progress chan
 	return nil
 }
 
-func (s *service) applyHDMod(path string, state chan PatchState, progress chan float32, manifestFiles []PatchFile) error {
+func (s *service) applyHDMod(ctx context.Context, path string, state chan PatchState, progress chan float32, manifestFiles []PatchFile) error {
 	// Update UI.
-	state <- PatchState{Message: "Checking HD mod..."}
+	sendState(ctx, state, PatchState{Message: "Checking HD mod..."})
 
 	// Figure out which files to patch.
 	patchFiles, patchLength, err := s.getFilesToPatch(manifestFiles, path, nil)
@@ -517,14 +701,9 @@ func (s *service) applyHDMod(path string, state chan PatchState, progress chan f
 
 	if len(patchFiles) > 0 {
 		// Update UI.
-		state <- PatchState{Message: fmt.Sprintf("Updating %s to latest HD mod version", path)}
-		if err = s.doPatch(patchFiles, patchLength, "hd", path, progress); err != nil {
-			patchErr := err
-			// Make sure we clean up the failed patch.
-			if err := s.cleanUpFailedPatch(path); err != nil {
-				return fmt.Errorf("Clean up error: %s : %s", patchErr, err)
-			}
-
+		sendState(ctx, state, PatchState{Message: fmt.Sprintf("Updating %s to latest HD mod version", path)})
+		if err = s.doPatch(ctx, patchFiles, patchLength, "hd", path, progress); err != nil {
+			// Downloaded .tmp files are kept, so the patch can be resumed.
 			return err
 		}
 	}
@@ -532,109 +711,198 @@ func (s *service) applyHDMod(path string, state chan PatchState, progress chan f
 	return nil
 }
 
-func (s *service) doPatch(patchFiles []string, patchLength int64, remoteDir string, path string, progress chan float32) error {
-	// Reset progress.
-	progress <- 0.00
-
+func (s *service) doPatch(ctx context.Context, patchFiles []PatchFile, patchLength int64, remoteDir string, path string, progress chan float32) error {
 	// Create a write counter that will get bytes written per cycle, pass the
 	// progress channel to report the number of bytes written.
 	counter := &WriteCounter{
 		Total:    float32(patchLength),
 		progress: progress,
+		ctx:      ctx,
 	}
 
-	// Store the downloaded .tmp suffixed files.
-	var tmpFiles []string
+	// Reset progress.
+	counter.Add(0)
+
+	// Download the files into .tmp suffixed files.
+	err := s.downloadFiles(ctx, patchFiles, remoteDir, path, counter)
 
-	// Patch the files.
-	for _, fileName := range patchFiles {
-		// Create the file, but give it a tmp file extension, this means we won't overwrite a
-		// file until it's downloaded, but we'll remove the tmp extension once downloaded.
-		tmpPath := localizePath(fmt.Sprintf("%s/%s.tmp", path, fileName))
+	// A cancelled patch might not have downloaded every file, none of them can be installed.
+	if err == nil {
+		err = ctx.Err()
+	}
 
-		err := s.downloadFile(fileName, remoteDir, tmpPath, counter)
-		if err != nil {
-			return err
+	if err != nil {
+		// The patch was cancelled, it won't be resumed so remove the downloads.
+		if ctx.Err() != nil {
+			if cleanUpErr := removeTmpFiles(path, patchFiles); cleanUpErr != nil {
+				return fmt.Errorf("Clean up error: %s : %s", err, cleanUpErr)
+			}
 		}
 
-		tmpFiles = append(tmpFiles, tmpPath)
+		return err
+	}
+
+	// Journal the replaced files, so the step can be rolled back if it fails midway.
+	j, err := beginJournal(path, remoteDir)
+	if err != nil {
+		return err
 	}
 
-	// All the files were successfully downloaded, remove the .tmp suffix
+	// All the files were successfully downloaded and verified, remove the .tmp suffix
 	// to complete the patch entirely.
-	for _, tmpFile := range tmpFiles {
-		err := os.Rename(tmpFile, tmpFile[:len(tmpFile)-4])
-		if err != nil {
+	for _, file := range patchFiles {
+		if err := j.replace(file.Name); err != nil {
+			if rollbackErr := j.rollback(); rollbackErr != nil {
+				return fmt.Errorf("Rollback error: %s : %s", err, rollbackErr)
+			}
+
+			return err
+		}
+
+		// The download is complete, it shouldn't be resumed anymore.
+		tmpPath := localizePath(fmt.Sprintf("%s/%s.tmp", path, file.Name))
+		if err := clearResumable(tmpPath); err != nil {
 			return err
 		}
 	}
 
-	return nil
+	return j.commit()
 }
 
-func (s *service) downloadFile(fileName string, remoteDir string, path string, counter *WriteCounter) error {
-	out, err := os.Create(path)
+// downloadVerifiedFile will download the file and verify it against the manifest,
+// files that fail verification are downloaded again a limited number of times.
+func (s *service) downloadVerifiedFile(ctx context.Context, file PatchFile, remoteDir string, path string, counter *WriteCounter) error {
+	for attempt := 1; ; attempt++ {
+		if err := s.downloadFile(ctx, file, remoteDir, path, counter); err != nil {
+			return err
+		}
+
+		err := verifyDownload(path, file)
+		if err == nil {
+			return nil
+		}
+
+		verificationErr, ok := err.(*VerificationError)
+		if !ok {
+			return err
+		}
+
+		// The downloaded file can't be trusted, remove it so we don't resume it.
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+
+		if err := clearResumable(path); err != nil {
+			return err
+		}
+
+		if attempt == maxDownloadAttempts {
+			return verificationErr
+		}
+
+		s.logger.Error(fmt.Errorf("%s, retrying download", verificationErr))
+
+		// Take the discarded bytes out of the progress.
+		counter.Add(-verificationErr.ActualSize)
+	}
+}
+
+func (s *service) downloadFile(ctx context.Context, file PatchFile, remoteDir string, path string, counter *WriteCounter) error {
+	f := fmt.Sprintf("%s/%s", remoteDir, file.Name)
+
+	// Identifies the contents of the tmp file, so we never resume
+	// a partial download of another file or an older version.
+	source := fmt.Sprintf("%s:%s", f, file.CRC)
+
+	// Check how much of the file was downloaded by a previous patch.
+	offset, err := resumeOffset(path, source)
 	if err != nil {
 		return err
 	}
 
-	defer out.Close()
+	// The partial download is larger than the file, it can't be resumed.
+	if offset > file.ContentLength {
+		offset = 0
+	}
 
-	f := fmt.Sprintf("%s/%s", remoteDir, fileName)
-	contents, err := s.slashdiabloClient.GetFile(f)
+	// The whole file has been downloaded already.
+	if offset > 0 && offset == file.ContentLength {
+		counter.Add(offset)
+		return nil
+	}
+
+	contents, start, err := s.slashdiabloClient.GetFile(ctx, f, offset)
+	if err == slashdiablo.ErrRangeNotSatisfiable {
+		// The server didn't agree with our offset, start over.
+		contents, start, err = s.slashdiabloClient.GetFile(ctx, f, 0)
+	}
 	if err != nil {
 		return err
 	}
 
-	_, err = io.Copy(out, io.TeeReader(contents, counter))
+	defer contents.Close()
+
+	// Append to the partial download if the server resumed it,
+	// otherwise the contents are the whole file and we start over.
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if start > 0 {
+		flags = os.O_WRONLY | os.O_APPEND
+	}
+
+	out, err := os.OpenFile(path, flags, 0644)
 	if err != nil {
 		return err
 	}
 
-	return nil
-}
+	defer out.Close()
 
-func (s *service) cleanUpFailedPatch(dir string) error {
-	files, err := ioutil.ReadDir(localizePath(dir))
-	if err != nil {
+	// Remember what we're downloading, in case we get interrupted.
+	if err := markResumable(path, source); err != nil {
 		return err
 	}
 
-	for _, f := range files {
-		fileName := f.Name()
-		if strings.Contains(fileName, ".tmp") {
-			err := os.Remove(localizePath(fmt.Sprintf("%s/%s", dir, fileName)))
-			if err != nil {
-				return err
-			}
-		}
+	// Seed the counter with the bytes we already have, to keep the progress correct.
+	counter.Add(start)
+
+	// Stop the download if the patch was cancelled or another download failed.
+	reader := &contextReader{ctx: ctx, r: contents}
+
+	_, err = io.Copy(out, io.TeeReader(reader, counter))
+	if err != nil {
+		return err
 	}
 
 	return nil
 }
 
-func (s *service) getFilesToPatch(files []PatchFile, d2path string, filesToIgnore []string) ([]string, int64, error) {
-	shouldPatch := make([]string, 0)
+func (s *service) getFilesToPatch(files []PatchFile, d2path string, filesToIgnore []string) ([]PatchFile, int64, error) {
+	missing, mismatched, err := s.compareFiles(files, d2path, filesToIgnore)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	shouldPatch := append(missing, mismatched...)
+
 	var totalContentLength int64
+	for _, f := range shouldPatch {
+		totalContentLength += f.ContentLength
+	}
+
+	return shouldPatch, totalContentLength, nil
+}
+
+// compareFiles will compare the patch files with the install, returning the files
+// missing on disk and the files on disk that differ from the patch.
+func (s *service) compareFiles(files []PatchFile, d2path string, filesToIgnore []string) ([]PatchFile, []PatchFile, error) {
+	missing := make([]PatchFile, 0)
+	mismatched := make([]PatchFile, 0)
 
 	for _, file := range files {
 		f := file
 
-		// Check if the file should be ignored or not.
-		if filesToIgnore != nil && len(filesToIgnore) > 0 {
-			var ignore bool
-			for _, ignored := range filesToIgnore {
-				// If the current file should be ignored, just skip it.
-				if f.Name == ignored {
-					ignore = true
-					break
-				}
-			}
-
-			// File should be ignored, continue with the next.
-			if ignore {
-				continue
-			}
+		// If the current file should be ignored, just skip it.
+		if isIgnored(f.Name, filesToIgnore) {
+			continue
 		}
 
 		// Full path on disk to the patch file.
@@ -646,49 +914,156 @@ func (s *service) getFilesToPatch(files []PatchFile, d2path string, filesToIgnor
 		if err != nil {
 			// If the file doesn't exist on disk, we need to patch it.
 			if err == ErrCRCFileNotFound {
-				shouldPatch = append(shouldPatch, f.Name)
-				totalContentLength += f.ContentLength
+				missing = append(missing, f)
 				continue
 			}
 
-			return nil, 0, err
+			return nil, nil, err
 		}
 
 		// File checksum differs from local copy, we need to get a new one.
 		if hashed != f.CRC {
-			shouldPatch = append(shouldPatch, f.Name)
-			totalContentLength += f.ContentLength
+			mismatched = append(mismatched, f)
 		}
 	}
 
-	return shouldPatch, totalContentLength, nil
+	return missing, mismatched, nil
 }
 
-func (s *service) getManifest(path string) (*Manifest, error) {
-	contents, err := s.slashdiabloClient.GetFile(path)
-	if err != nil {
-		return nil, err
+// isIgnored returns true if the file name is one of the ignored files.
+func isIgnored(name string, filesToIgnore []string) bool {
+	for _, ignored := range filesToIgnore {
+		if name == ignored {
+			return true
+		}
 	}
 
-	bytes, err := ioutil.ReadAll(contents)
-	if err != nil {
-		return nil, err
-	}
+	return false
+}
 
+// getManifest returns the manifest at the path, from the cache if it hasn't changed on the server.
+func (s *service) getManifest(ctx context.Context, run *manifestRun, path string) (*Manifest, error) {
 	var manifest Manifest
-	if err := json.Unmarshal(bytes, &manifest); err != nil {
+	if err := run.get(ctx, path, &manifest); err != nil {
 		return nil, err
 	}
 
 	return &manifest, nil
 }
 
+// updateVersions will add the Game.exe hashes from the patch repository to the
+// version detector, the list is optional so only cancellation is returned as an error.
+func (s *service) updateVersions(ctx context.Context, run *manifestRun) error {
+	var versions VersionList
+	if err := run.get(ctx, "versions.json", &versions); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		if !slashdiablo.IsNotFound(err) {
+			s.logger.Error(fmt.Errorf("couldn't get version list: %s", err))
+		}
+
+		return nil
+	}
+
+	s.versions.add(versions.Hashes)
+
+	return nil
+}
+
+// sendState sends the patch state, unless the patch has been cancelled.
+func sendState(ctx context.Context, state chan PatchState, current PatchState) {
+	if ctx.Err() != nil {
+		return
+	}
+
+	select {
+	case state <- current:
+	case <-ctx.Done():
+	}
+}
+
+// removeTmpFiles removes the downloaded .tmp files of the patch files in the given path.
+func removeTmpFiles(path string, files []PatchFile) error {
+	for _, file := range files {
+		tmpPath := localizePath(fmt.Sprintf("%s/%s.tmp", path, file.Name))
+
+		if err := os.Remove(tmpPath); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+
+		if err := clearResumable(tmpPath); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Launch statuses of an instance.
+const (
+	// LaunchStarting is used before the instance is started.
+	LaunchStarting = "starting"
+
+	// LaunchWaiting is used while waiting for the instance to be ready.
+	LaunchWaiting = "waiting"
+
+	// LaunchReady is used once the instance is ready.
+	LaunchReady = "ready"
+
+	// LaunchFailed is used when launching failed, the error is set.
+	LaunchFailed = "failed"
+)
+
+// LaunchState represents the state of an instance being launched.
+type LaunchState struct {
+	GameID   string
+	Location string
+	Label    string
+
+	// Instance is the number of the instance, out of the instances of the game.
+	Instance  int
+	Instances int
+
+	PID    int
+	Status string
+	Error  error
+}
+
+// sendLaunchState sends the launch state, unless the launch has been cancelled.
+func sendLaunchState(ctx context.Context, state chan LaunchState, current LaunchState) {
+	select {
+	case state <- current:
+	case <-ctx.Done():
+	}
+}
+
 // PatchState represents the state given on every patch cycle.
 type PatchState struct {
 	Message string
 	Error   error
 }
 
+// newPatchErrorState returns the patch state for the error, with a message
+// telling the user what went wrong.
+func newPatchErrorState(err error) PatchState {
+	message := "Couldn't patch game files"
+
+	switch e := err.(type) {
+	case *slashdiablo.StatusError:
+		if e.NotFound() {
+			message = fmt.Sprintf("Patch file %s is missing on the server", e.URL)
+		} else if e.ServerError() {
+			message = "The patch server is down, try again later"
+		}
+	case *VerificationError:
+		message = fmt.Sprintf("Downloaded %s was corrupt", e.File)
+	}
+
+	return PatchState{Message: message, Error: err}
+}
+
 // Manifest represents the current patch.
 type Manifest struct {
 	Files []PatchFile `json:"files"`
@@ -707,17 +1082,14 @@ func NewService(
 	slashdiabloClient slashdiablo.Client,
 	configuration config.Service,
 	logger log.Logger,
+	configPath string,
 ) Service {
-	s := &service{
+	return &service{
 		slashdiabloClient: slashdiabloClient,
 		configService:     configuration,
 		logger:            logger,
-		gameStates:        make(chan execState, 4),
+		manifests:         newManifestCache(configPath, slashdiabloClient, logger),
+		versions:          newVersionDetector(),
+		supervisor:        newSupervisor(logger),
 	}
-
-	// Setup game listener once, will stay alive for the duration
-	// of the service's life cycle.
-	go s.listenForGameStates()
-
-	return s
 }
@@ -0,0 +1,583 @@
+package d2
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/nokka/slashdiablo-launcher/log"
+)
+
+const (
+	// stopTimeout is how long an instance gets to exit gracefully before it's killed.
+	stopTimeout = 5 * time.Second
+
+	// stderrTimeout is how long to wait for the rest of the errors once an instance has exited.
+	stderrTimeout = 1 * time.Second
+)
+
+// ErrInstanceNotFound is returned when stopping an instance that isn't running.
+var ErrInstanceNotFound = errors.New("instance not found")
+
+// Instance is a game instance started by the launcher.
+type Instance struct {
+	PID       int
+	GameID    string
+	StartedAt time.Time
+
+	// Slot is the number of the instance in the game, starting at 0, with the label of its profile.
+	Slot  int
+	Label string
+
+	// Set once the instance has exited.
+	ExitCode int
+	Stderr   string
+}
+
+// process is a running instance and the command it was started with.
+type process struct {
+	instance Instance
+	cmd      *exec.Cmd
+	command  *command
+
+	// The number of times the instance has been restarted in a row.
+	restarts int
+
+	// Closed once the process has exited.
+	exited chan struct{}
+
+	// Set once the instance is ready, instances exiting cleanly before then have
+	// handed off the game to a process they started.
+	ready bool
+
+	// Set when the instance was stopped from the launcher.
+	stopped bool
+}
+
+// supervisor starts game instances and keeps track of them until they exit.
+type supervisor struct {
+	logger    log.Logger
+	processes map[int]*process
+	pending   map[*pendingRestart]struct{}
+	crashes   []Crash
+	watchers  []chan []Instance
+	mux       sync.Mutex
+
+	// launching is held while an instance of the game is started and waited on, so
+	// instances of a game, launched or restarted, never start at the same time.
+	launching map[string]*sync.Mutex
+}
+
+// launch will start an instance of the game and wait until it's ready, started is called
+// with the instance once it's running. The instance is restarted according to the policy of the command.
+func (s *supervisor) launch(ctx context.Context, c *command, started func(Instance)) error {
+	launching := s.launchLock(c.gameID)
+	launching.Lock()
+	defer launching.Unlock()
+
+	p, err := s.start(c)
+	if err != nil {
+		return err
+	}
+
+	s.mux.Lock()
+	instance := p.instance
+	s.mux.Unlock()
+
+	started(instance)
+
+	return s.awaitReady(ctx, p, instance)
+}
+
+// start will start an instance of the game, the instance is tracked before the command
+// can exit so no exit goes unnoticed.
+func (s *supervisor) start(c *command) (*process, error) {
+	cmd, err := c.create()
+	if err != nil {
+		return nil, err
+	}
+
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	return s.startProcess(c, cmd, 0)
+}
+
+// launchLock returns the lock held while launching instances of the game.
+func (s *supervisor) launchLock(gameID string) *sync.Mutex {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	l, ok := s.launching[gameID]
+	if !ok {
+		l = &sync.Mutex{}
+		s.launching[gameID] = l
+	}
+
+	return l
+}
+
+// awaitReady blocks until the started instance is ready. An instance exiting cleanly before it's
+// ready has handed off the game to a process it started, such as Diablo II.exe starting Game.exe,
+// that process can't be watched so it's given the launch delay to start.
+func (s *supervisor) awaitReady(ctx context.Context, p *process, instance Instance) error {
+	err := p.command.checker.Ready(ctx, instance, p.exited)
+
+	if err == ErrExitedBeforeReady {
+		s.mux.Lock()
+		handedOff := p.instance.ExitCode == 0 && !p.stopped
+		s.mux.Unlock()
+
+		if handedOff {
+			err = sleep(ctx, p.command.delay-time.Since(instance.StartedAt))
+		}
+	}
+
+	if err != nil {
+		return err
+	}
+
+	s.mux.Lock()
+	p.ready = true
+	s.mux.Unlock()
+
+	return nil
+}
+
+// startProcess must be called holding the lock.
+func (s *supervisor) startProcess(c *command, cmd *exec.Cmd, restarts int) (*process, error) {
+	// Collect the errors from the command through our own pipe, if the command is
+	// given a buffer, waiting on it also waits on the processes it started.
+	r, w, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+
+	cmd.Stderr = w
+
+	err = cmd.Start()
+
+	// The command has its own copy of the pipe now.
+	w.Close()
+
+	if err != nil {
+		r.Close()
+		return nil, err
+	}
+
+	stderr := newOutput(r)
+
+	p := &process{
+		instance: Instance{
+			PID:       cmd.Process.Pid,
+			GameID:    c.gameID,
+			StartedAt: time.Now(),
+			Slot:      c.slot,
+			Label:     c.label,
+		},
+		cmd:      cmd,
+		command:  c,
+		restarts: restarts,
+		exited:   make(chan struct{}),
+	}
+
+	s.processes[p.instance.PID] = p
+	s.publish()
+
+	// Wait on separate thread, the instance is removed once it exits.
+	go s.wait(p, stderr)
+
+	return p, nil
+}
+
+func (s *supervisor) wait(p *process, stderr *output) {
+	err := p.cmd.Wait()
+
+	// Processes started by the game might keep the pipe open, don't wait on them for long.
+	select {
+	case <-stderr.done:
+	case <-time.After(stderrTimeout):
+	}
+
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	delete(s.processes, p.instance.PID)
+
+	// Publish once the crash is recorded, watchers read the crash history when the instances change.
+	defer s.publish()
+
+	p.instance.ExitCode = p.cmd.ProcessState.ExitCode()
+	p.instance.Stderr = stderr.String()
+
+	close(p.exited)
+
+	// Stopped instances are expected to exit with an error, and stay stopped.
+	if p.stopped {
+		return
+	}
+
+	crashed := err != nil
+
+	switch err.(type) {
+	case nil:
+	case *exec.ExitError:
+		// The program has exited with an exit code != 0.
+		s.logger.Error(fmt.Errorf("Diablo II exited with status %d : %s", p.instance.ExitCode, p.instance.Stderr))
+	default:
+		// Was some other wait error such as permissions.
+		s.logger.Error(fmt.Errorf("Diablo II wait failed: %s : %s", err, p.instance.Stderr))
+	}
+
+	// Instances that ran for a while have recovered, restarts are only limited in a row.
+	restarts := p.restarts
+	if time.Since(p.instance.StartedAt) >= restartResetAfter {
+		restarts = 0
+	}
+
+	// The game is running in the process it was handed off to, another instance would be a duplicate.
+	handedOff := !crashed && !p.ready
+	if handedOff {
+		s.logger.Info(fmt.Sprintf("%s instance %d handed off to another process, it won't be restarted", p.instance.GameID, p.instance.PID))
+	}
+
+	restart := !handedOff && p.command.policy.shouldRestart(crashed, restarts)
+
+	if crashed {
+		s.recordCrash(p.instance, restart)
+	}
+
+	if restart {
+		s.scheduleRestart(p, restarts+1)
+	} else if crashed && p.command.policy.restarts() {
+		s.logger.Info(fmt.Sprintf("not restarting %s, it has been restarted %d times in a row", p.instance.GameID, restarts))
+	}
+}
+
+// recordCrash adds the crashed instance to the crash history, dropping the oldest crashes.
+// The history only lasts the session, so the crash is logged as well. Must be called holding the lock.
+func (s *supervisor) recordCrash(instance Instance, restarted bool) {
+	crash := Crash{
+		GameID:    instance.GameID,
+		Label:     instance.Label,
+		PID:       instance.PID,
+		StartedAt: instance.StartedAt,
+		ExitedAt:  time.Now(),
+		ExitCode:  instance.ExitCode,
+		Stderr:    instance.Stderr,
+		Restarted: restarted,
+	}
+
+	s.crashes = append(s.crashes, crash)
+
+	s.logger.Info(fmt.Sprintf(
+		"%s instance %d crashed after %s with status %d, restarted: %t",
+		crash.GameID, crash.PID, crash.ExitedAt.Sub(crash.StartedAt).Round(time.Second), crash.ExitCode, crash.Restarted,
+	))
+
+	if len(s.crashes) > maxCrashHistory {
+		s.crashes = s.crashes[len(s.crashes)-maxCrashHistory:]
+	}
+}
+
+// scheduleRestart will restart the exited process after backing off, unless it's stopped
+// from the launcher before then. Must be called holding the lock.
+func (s *supervisor) scheduleRestart(p *process, restarts int) {
+	r := &pendingRestart{
+		instance: p.instance,
+		cancel:   make(chan struct{}),
+	}
+
+	s.pending[r] = struct{}{}
+
+	delay := backoff(restarts)
+	s.logger.Info(fmt.Sprintf("restarting %s in %s, restart %d of %d", p.instance.GameID, delay, restarts, p.command.policy.maxRestarts))
+
+	go func() {
+		select {
+		case <-time.After(delay):
+		case <-r.cancel:
+			return
+		}
+
+		if err := s.restart(r, p.command, restarts); err != nil {
+			s.logger.Error(fmt.Errorf("couldn't restart %s: %s", p.instance.GameID, err))
+		}
+	}()
+}
+
+// restart will start the pending restart, unless it has been cancelled. Restarts are launched
+// like any other instance, one at a time once the previous instance of the game is ready.
+func (s *supervisor) restart(r *pendingRestart, c *command, restarts int) error {
+	launching := s.launchLock(c.gameID)
+	launching.Lock()
+	defer launching.Unlock()
+
+	cmd, err := c.create()
+
+	s.mux.Lock()
+
+	// Cancelled while waiting for the other instances, or while the command was created.
+	if _, ok := s.pending[r]; !ok {
+		s.mux.Unlock()
+		return nil
+	}
+
+	delete(s.pending, r)
+
+	if err != nil {
+		s.mux.Unlock()
+		return err
+	}
+
+	p, err := s.startProcess(c, cmd, restarts)
+	if err != nil {
+		s.mux.Unlock()
+		return err
+	}
+
+	instance := p.instance
+
+	s.mux.Unlock()
+
+	// An instance exiting before it's ready is handled by its policy like any other exit.
+	if err := s.awaitReady(context.Background(), p, instance); err != nil && err != ErrExitedBeforeReady {
+		return err
+	}
+
+	return nil
+}
+
+// stop will stop the instance with the pid.
+func (s *supervisor) stop(pid int) error {
+	s.mux.Lock()
+
+	p, ok := s.processes[pid]
+	if !ok {
+		s.mux.Unlock()
+		return ErrInstanceNotFound
+	}
+
+	p.stopped = true
+
+	s.mux.Unlock()
+
+	return s.terminate(p)
+}
+
+// stopGame will stop all instances of the game.
+func (s *supervisor) stopGame(gameID string) error {
+	return s.stopWhere(func(instance Instance) bool {
+		return instance.GameID == gameID
+	})
+}
+
+// stopAll will stop all instances.
+func (s *supervisor) stopAll() error {
+	return s.stopWhere(func(instance Instance) bool {
+		return true
+	})
+}
+
+// stopWhere will stop the instances matching, all at once so they don't wait on each other.
+// Matching instances waiting to be restarted aren't restarted.
+func (s *supervisor) stopWhere(match func(instance Instance) bool) error {
+	s.mux.Lock()
+
+	for r := range s.pending {
+		if match(r.instance) {
+			delete(s.pending, r)
+			close(r.cancel)
+		}
+	}
+
+	var matching []*process
+	for _, p := range s.processes {
+		if match(p.instance) {
+			p.stopped = true
+			matching = append(matching, p)
+		}
+	}
+
+	s.mux.Unlock()
+
+	errs := make(chan error, len(matching))
+
+	for _, p := range matching {
+		go func(p *process) {
+			errs <- s.terminate(p)
+		}(p)
+	}
+
+	var firstErr error
+	for range matching {
+		if err := <-errs; err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	return firstErr
+}
+
+// terminate will ask the process to exit, and kill it if it hasn't exited in time.
+func (s *supervisor) terminate(p *process) error {
+	// The process might not handle being asked to exit, it's killed regardless.
+	if err := terminate(p.cmd.Process); err != nil {
+		s.logger.Error(fmt.Errorf("couldn't terminate %d gracefully: %s", p.instance.PID, err))
+	}
+
+	select {
+	case <-p.exited:
+		return nil
+	case <-time.After(stopTimeout):
+	}
+
+	if err := p.cmd.Process.Kill(); err != nil {
+		// The process exited after the timeout, but before we killed it.
+		select {
+		case <-p.exited:
+			return nil
+		default:
+			return err
+		}
+	}
+
+	<-p.exited
+
+	return nil
+}
+
+// isRunning reports if the instance in the slot of the game is running,
+// or waiting to be restarted.
+func (s *supervisor) isRunning(gameID string, slot int) bool {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	for _, p := range s.processes {
+		if p.instance.GameID == gameID && p.instance.Slot == slot {
+			return true
+		}
+	}
+
+	for r := range s.pending {
+		if r.instance.GameID == gameID && r.instance.Slot == slot {
+			return true
+		}
+	}
+
+	return false
+}
+
+// crashHistory returns the instances that exited unexpectedly, the latest crash last.
+func (s *supervisor) crashHistory() []Crash {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	crashes := make([]Crash, len(s.crashes))
+	copy(crashes, s.crashes)
+
+	return crashes
+}
+
+// list returns the running instances, in the order they were started.
+func (s *supervisor) list() []Instance {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	return s.snapshot()
+}
+
+// watch returns a channel receiving the running instances every time they change.
+func (s *supervisor) watch() <-chan []Instance {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	// Buffer the latest change, so a slow watcher never blocks the supervisor.
+	w := make(chan []Instance, 1)
+	w <- s.snapshot()
+
+	s.watchers = append(s.watchers, w)
+
+	return w
+}
+
+// publish sends the running instances to the watchers, replacing changes they haven't received yet.
+// Must be called holding the lock.
+func (s *supervisor) publish() {
+	instances := s.snapshot()
+
+	for _, w := range s.watchers {
+		select {
+		case <-w:
+		default:
+		}
+
+		w <- instances
+	}
+}
+
+// snapshot must be called holding the lock.
+func (s *supervisor) snapshot() []Instance {
+	instances := make([]Instance, 0, len(s.processes))
+	for _, p := range s.processes {
+		instances = append(instances, p.instance)
+	}
+
+	sort.Slice(instances, func(i, j int) bool {
+		return instances[i].StartedAt.Before(instances[j].StartedAt)
+	})
+
+	return instances
+}
+
+// output collects what a process writes to a pipe, until the pipe is closed.
+type output struct {
+	buf  bytes.Buffer
+	done chan struct{}
+	mux  sync.Mutex
+}
+
+func (o *output) Write(p []byte) (int, error) {
+	o.mux.Lock()
+	defer o.mux.Unlock()
+
+	return o.buf.Write(p)
+}
+
+func (o *output) String() string {
+	o.mux.Lock()
+	defer o.mux.Unlock()
+
+	return o.buf.String()
+}
+
+func newOutput(r *os.File) *output {
+	o := &output{
+		done: make(chan struct{}),
+	}
+
+	go func() {
+		defer close(o.done)
+		defer r.Close()
+
+		io.Copy(o, r)
+	}()
+
+	return o
+}
+
+func newSupervisor(logger log.Logger) *supervisor {
+	return &supervisor{
+		logger:    logger,
+		processes: make(map[int]*process),
+		pending:   make(map[*pendingRestart]struct{}),
+		launching: make(map[string]*sync.Mutex),
+	}
+}
@@ -0,0 +1,18 @@
+package d2
+
+import "github.com/therecipe/qt/core"
+
+// ValidationItem represents the validation report of a game in the model.
+type ValidationItem struct {
+	core.QObject
+
+	GameID             string
+	Location           string
+	Version            string
+	UpToDate           bool
+	DownloadSize       int64
+	OutdatedComponents []string
+	MissingFiles       []string
+	MismatchedFiles    []string
+	UnexpectedFiles    []string
+}
@@ -0,0 +1,117 @@
+package d2
+
+import (
+	"github.com/therecipe/qt/core"
+)
+
+// Model Roles.
+const (
+	ValidationGameID = int(core.Qt__UserRole) + 1<<iota
+	ValidationLocation
+	ValidationVersion
+	ValidationUpToDate
+	ValidationDownloadSize
+	ValidationOutdatedComponents
+	ValidationMissingFiles
+	ValidationMismatchedFiles
+	ValidationUnexpectedFiles
+)
+
+// ValidationModel is the model of the validation report, one item per game.
+type ValidationModel struct {
+	core.QAbstractListModel
+
+	_ func() `constructor:"init"`
+
+	_ map[int]*core.QByteArray `property:"roles"`
+	_ []*ValidationItem        `property:"items"`
+
+	_ func(*ValidationItem) `slot:"addItem"`
+	_ func()                `slot:"clear"`
+}
+
+func (m *ValidationModel) init() {
+	m.SetRoles(map[int]*core.QByteArray{
+		ValidationGameID:             core.NewQByteArray2("gameId", -1),
+		ValidationLocation:           core.NewQByteArray2("location", -1),
+		ValidationVersion:            core.NewQByteArray2("version", -1),
+		ValidationUpToDate:           core.NewQByteArray2("upToDate", -1),
+		ValidationDownloadSize:       core.NewQByteArray2("downloadSize", -1),
+		ValidationOutdatedComponents: core.NewQByteArray2("outdatedComponents", -1),
+		ValidationMissingFiles:       core.NewQByteArray2("missingFiles", -1),
+		ValidationMismatchedFiles:    core.NewQByteArray2("mismatchedFiles", -1),
+		ValidationUnexpectedFiles:    core.NewQByteArray2("unexpectedFiles", -1),
+	})
+
+	m.ConnectData(m.data)
+	m.ConnectRowCount(m.rowCount)
+	m.ConnectColumnCount(m.columnCount)
+	m.ConnectRoleNames(m.roleNames)
+	m.ConnectAddItem(m.addItem)
+	m.ConnectClear(m.clear)
+}
+
+func (m *ValidationModel) rowCount(*core.QModelIndex) int {
+	return len(m.Items())
+}
+
+func (m *ValidationModel) columnCount(*core.QModelIndex) int {
+	return 1
+}
+
+func (m *ValidationModel) roleNames() map[int]*core.QByteArray {
+	return m.Roles()
+}
+
+func (m *ValidationModel) data(index *core.QModelIndex, role int) *core.QVariant {
+	if !index.IsValid() {
+		return core.NewQVariant()
+	}
+
+	if index.Row() >= len(m.Items()) {
+		return core.NewQVariant()
+	}
+
+	item := m.Items()[index.Row()]
+
+	switch role {
+	case ValidationGameID:
+		return core.NewQVariant1(item.GameID)
+	case ValidationLocation:
+		return core.NewQVariant1(item.Location)
+	case ValidationVersion:
+		return core.NewQVariant1(item.Version)
+	case ValidationUpToDate:
+		return core.NewQVariant1(item.UpToDate)
+	case ValidationDownloadSize:
+		return core.NewQVariant1(item.DownloadSize)
+	case ValidationOutdatedComponents:
+		return core.NewQVariant1(item.OutdatedComponents)
+	case ValidationMissingFiles:
+		return core.NewQVariant1(item.MissingFiles)
+	case ValidationMismatchedFiles:
+		return core.NewQVariant1(item.MismatchedFiles)
+	case ValidationUnexpectedFiles:
+		return core.NewQVariant1(item.UnexpectedFiles)
+	default:
+		return core.NewQVariant()
+	}
+}
+
+// addItem adds an item to the model.
+func (m *ValidationModel) addItem(i *ValidationItem) {
+	m.BeginInsertRows(core.NewQModelIndex(), len(m.Items()), len(m.Items()))
+	m.SetItems(append(m.Items(), i))
+	m.EndInsertRows()
+}
+
+func (m *ValidationModel) clear() {
+	m.BeginResetModel()
+	m.SetItems([]*ValidationItem{})
+	m.EndResetModel()
+}
+
+func init() {
+	ValidationModel_QRegisterMetaType()
+	ValidationItem_QRegisterMetaType()
+}
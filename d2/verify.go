@@ -0,0 +1,64 @@
+package d2
+
+import (
+	"fmt"
+	"os"
+)
+
+const (
+	// maxDownloadAttempts is the number of times we'll download a file that fails verification.
+	maxDownloadAttempts = 3
+)
+
+// VerificationError is used when a downloaded file doesn't match the manifest.
+type VerificationError struct {
+	File         string
+	ExpectedCRC  string
+	ActualCRC    string
+	ExpectedSize int64
+	ActualSize   int64
+}
+
+// Error returns the description of the mismatch.
+func (e *VerificationError) Error() string {
+	if e.ExpectedSize != e.ActualSize {
+		return fmt.Sprintf("verification of %s failed: expected %d bytes, got %d", e.File, e.ExpectedSize, e.ActualSize)
+	}
+
+	return fmt.Sprintf("verification of %s failed: expected crc %s, got %s", e.File, e.ExpectedCRC, e.ActualCRC)
+}
+
+// verifyDownload makes sure the downloaded file on the given path matches the patch file.
+func verifyDownload(path string, file PatchFile) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	// Compare the size first, it's cheap and catches truncated downloads.
+	if info.Size() != file.ContentLength {
+		return &VerificationError{
+			File:         file.Name,
+			ExpectedCRC:  file.CRC,
+			ExpectedSize: file.ContentLength,
+			ActualSize:   info.Size(),
+		}
+	}
+
+	hashed, err := hashCRC32(path, polynomial)
+	if err != nil {
+		return err
+	}
+
+	if hashed != file.CRC {
+		return &VerificationError{
+			File:         file.Name,
+			ExpectedCRC:  file.CRC,
+			ActualCRC:    hashed,
+			ExpectedSize: file.ContentLength,
+			ActualSize:   info.Size(),
+		}
+	}
+
+	return nil
+}
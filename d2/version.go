@@ -0,0 +1,79 @@
+package d2
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+)
+
+// SHA1 of the different versions of Diablo Game.exe.
+var hashList = map[string]string{
+	"a875b98fa3a8b9300bcc04c84be1fa057eb277b5": "1.12",
+	"af2b33c90b50ede8d9a8bca9b8d9720c87f78641": "1.13c",
+	"27ddadbc457affed122564ae7a4bd2223181e15a": "1.13c", // Custom 1.13c build with HD icon.
+	"11cd918cb6906295769d9be1b3e349e02af6b229": "1.13d",
+	"3e64f12c6ef72847f49d301c2472280d4460589d": "1.14a",
+	"11e940266c6838414c2114c2172227f982d4054e": "1.14b",
+	"255691dd53e3bcd646e5c6e1e2e7b16da745b706": "1.14c",
+	"af0ea93d2a652ceb11ac01ee2e4ae1ef613444c2": "1.14d",
+}
+
+// VersionList is the list of Game.exe hashes served by the patch repository,
+// used to recognize versions the launcher wasn't built with.
+type VersionList struct {
+	Hashes map[string]string `json:"hashes"`
+}
+
+// versionDetector recognizes the Diablo II version of a Game.exe by its SHA1.
+type versionDetector struct {
+	hashes map[string]string
+	mux    sync.RWMutex
+}
+
+// add will add the known hashes, overriding the existing version of a hash.
+func (d *versionDetector) add(hashes map[string]string) {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+
+	for hash, version := range hashes {
+		d.hashes[hash] = version
+	}
+}
+
+// gameVersion will return the given installations Diablo II version, empty if unknown.
+func (d *versionDetector) gameVersion(path string) (string, error) {
+	return d.detect(localizePath(fmt.Sprintf("%s/%s", path, "Game.exe")))
+}
+
+// detect will return the Diablo II version of the Game.exe at the given path,
+// empty if the file doesn't exist or the version is unknown.
+func (d *versionDetector) detect(exePath string) (string, error) {
+	content, err := ioutil.ReadFile(exePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", err
+	}
+
+	// Hash the content of the Game.exe.
+	hashed := fmt.Sprintf("%x", sha1.Sum(content))
+
+	d.mux.RLock()
+	defer d.mux.RUnlock()
+
+	// Check the game version, unknown versions are empty.
+	return d.hashes[hashed], nil
+}
+
+func newVersionDetector() *versionDetector {
+	d := &versionDetector{
+		hashes: make(map[string]string, len(hashList)),
+	}
+
+	d.add(hashList)
+
+	return d
+}
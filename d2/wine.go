@@ -0,0 +1,59 @@
+package d2
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/nokka/slashdiablo-launcher/storage"
+)
+
+const (
+	// defaultWineBinary is used when no Wine binary has been configured.
+	defaultWineBinary = "wine"
+
+	// defaultWinePrefix is the prefix in the home directory Wine uses when none has been configured.
+	defaultWinePrefix = ".wine"
+)
+
+// wineConfig is how Diablo II is run through Wine, on systems that can't run it natively.
+type wineConfig struct {
+	Binary string
+	Prefix string
+}
+
+// newWineConfig returns the Wine setup from the config, the WINE and
+// WINEPREFIX environment variables take precedence over the config.
+func newWineConfig(conf *storage.Config) wineConfig {
+	wine := wineConfig{
+		Binary: conf.WineBinary,
+		Prefix: conf.WinePrefix,
+	}
+
+	if binary := os.Getenv("WINE"); binary != "" {
+		wine.Binary = binary
+	}
+
+	if prefix := os.Getenv("WINEPREFIX"); prefix != "" {
+		wine.Prefix = prefix
+	}
+
+	if wine.Binary == "" {
+		wine.Binary = defaultWineBinary
+	}
+
+	return wine
+}
+
+// prefixPath returns the directory of the Wine prefix, the default prefix if none is configured.
+func (w wineConfig) prefixPath() (string, error) {
+	if w.Prefix != "" {
+		return w.Prefix, nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(home, defaultWinePrefix), nil
+}
@@ -0,0 +1,177 @@
+package d2
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	// wineUserRegistry is the file in the Wine prefix holding the HKEY_CURRENT_USER registry.
+	wineUserRegistry = "user.reg"
+
+	// wineRegistryBackupSuffix is appended to the registry file to back it up before it's first written.
+	wineRegistryBackupSuffix = ".orig"
+
+	// wineRegistryLineLength is where Wine wraps long hex values.
+	wineRegistryLineLength = 76
+)
+
+// wineRegistry edits a registry file in a Wine prefix. Wine keeps the registry in memory
+// while it's running and writes it back on exit, so it should only be edited while Wine is stopped.
+type wineRegistry struct {
+	path  string
+	lines []string
+}
+
+// setString will set the string value with the name in the key.
+func (r *wineRegistry) setString(key string, name string, value string) {
+	r.setValue(key, name, fmt.Sprintf("\"%s\"", escapeRegistryString(value)))
+}
+
+// setBinary will set the binary value with the name in the key.
+func (r *wineRegistry) setBinary(key string, name string, value []byte) {
+	prefix := fmt.Sprintf("\"%s\"=hex:", escapeRegistryString(name))
+
+	var b strings.Builder
+	lineLength := len(prefix)
+
+	for i, v := range value {
+		if i > 0 {
+			b.WriteString(",")
+			lineLength++
+
+			// Wrap long values the same way Wine does.
+			if lineLength+3 > wineRegistryLineLength {
+				b.WriteString("\\\n  ")
+				lineLength = 2
+			}
+		}
+
+		fmt.Fprintf(&b, "%02x", v)
+		lineLength += 2
+	}
+
+	r.setLine(key, name, prefix+b.String())
+}
+
+func (r *wineRegistry) setValue(key string, name string, value string) {
+	r.setLine(key, name, fmt.Sprintf("\"%s\"=%s", escapeRegistryString(name), value))
+}
+
+// setLine will replace the value with the name in the key with the line,
+// the key is created if it doesn't exist.
+func (r *wineRegistry) setLine(key string, name string, line string) {
+	header := fmt.Sprintf("[%s]", strings.Replace(key, `\`, `\\`, -1))
+	valuePrefix := fmt.Sprintf("\"%s\"=", escapeRegistryString(name))
+
+	start := -1
+	for i, l := range r.lines {
+		// Key names aren't case sensitive.
+		if strings.HasPrefix(strings.ToLower(l), strings.ToLower(header)) {
+			start = i + 1
+			break
+		}
+	}
+
+	// The key doesn't exist, add it to the end of the file.
+	if start == -1 {
+		if len(r.lines) > 0 && r.lines[len(r.lines)-1] != "" {
+			r.lines = append(r.lines, "")
+		}
+
+		r.lines = append(r.lines, fmt.Sprintf("%s %d", header, time.Now().Unix()), line)
+		return
+	}
+
+	// Find the end of the key, trailing empty lines belong to the next key.
+	end := start
+	for end < len(r.lines) && !strings.HasPrefix(r.lines[end], "[") {
+		end++
+	}
+	for end > start && r.lines[end-1] == "" {
+		end--
+	}
+
+	for i := start; i < end; i++ {
+		if !strings.HasPrefix(strings.ToLower(r.lines[i]), strings.ToLower(valuePrefix)) {
+			continue
+		}
+
+		// Long values continue on the next lines, ending the line with a backslash.
+		last := i
+		for last < end-1 && strings.HasSuffix(r.lines[last], "\\") {
+			last++
+		}
+
+		r.lines = append(r.lines[:i], append([]string{line}, r.lines[last+1:]...)...)
+		return
+	}
+
+	r.lines = append(r.lines[:end], append([]string{line}, r.lines[end:]...)...)
+}
+
+// save will write the registry back to disk. The registry is backed up the first time it's written,
+// later backups would only contain the launcher's own changes.
+func (r *wineRegistry) save() error {
+	backup := r.path + wineRegistryBackupSuffix
+
+	_, err := os.Stat(backup)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	if os.IsNotExist(err) {
+		original, err := ioutil.ReadFile(r.path)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+
+		if err == nil {
+			if err := ioutil.WriteFile(backup, original, 0644); err != nil {
+				return err
+			}
+		}
+	}
+
+	contents := strings.Join(r.lines, "\n")
+	if !strings.HasSuffix(contents, "\n") {
+		contents += "\n"
+	}
+
+	// Replace the registry in a single rename, Wine can't read a half written registry.
+	if err := ioutil.WriteFile(r.path+".tmp", []byte(contents), 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(r.path+".tmp", r.path)
+}
+
+// escapeRegistryString escapes the string the way Wine stores strings in the registry files.
+func escapeRegistryString(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+}
+
+// openWineRegistry opens the user registry of the Wine prefix,
+// if the prefix hasn't got one yet an empty registry is returned.
+func openWineRegistry(prefix string) (*wineRegistry, error) {
+	path := filepath.Join(prefix, wineUserRegistry)
+
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			return nil, err
+		}
+
+		contents = []byte("WINE REGISTRY Version 2\n")
+	}
+
+	return &wineRegistry{
+		path:  path,
+		lines: strings.Split(string(bytes.TrimRight(contents, "\n")), "\n"),
+	}, nil
+}
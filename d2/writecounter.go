@@ -1,11 +1,19 @@
 package d2
 
+import (
+	"context"
+	"sync"
+)
+
 // WriteCounter counts the number of bytes written to it. It implements to the io.Writer
 // interface and we can pass this into io.TeeReader() which will report progress on each write cycle.
+// It's safe to share between concurrent downloads.
 type WriteCounter struct {
 	Total    float32
 	Written  float32
 	progress chan float32
+	ctx      context.Context
+	mux      sync.Mutex
 }
 
 // Write gets every write cycle reported on it.
@@ -14,11 +22,25 @@ func (wc *WriteCounter) Write(p []byte) (int, error) {
 	n := len(p)
 
 	// Add the written bytes to the total.
-	wc.Written += float32(n)
-
-	// Calculate the percentage and send it on the channel.
-	wc.progress <- wc.Written / wc.Total
+	wc.Add(int64(n))
 
 	// Return the length of the written bytes this cycle.
 	return n, nil
 }
+
+// Add adds bytes that were written elsewhere to the counter, such as
+// bytes already downloaded by a previous patch.
+func (wc *WriteCounter) Add(n int64) {
+	// Lock so concurrent writes report progress in order.
+	wc.mux.Lock()
+	defer wc.mux.Unlock()
+
+	// Add the written bytes to the total.
+	wc.Written += float32(n)
+
+	// Calculate the percentage and send it on the channel, unless nobody's listening anymore.
+	select {
+	case wc.progress <- wc.Written / wc.Total:
+	case <-wc.ctx.Done():
+	}
+}
@@ -3,11 +3,13 @@ package main
 import (
 	"bufio"
 	"errors"
+	"fmt"
 	"os"
 	"strconv"
 
 	"github.com/nokka/goqmlframeless"
 	"github.com/nokka/slashdiablo-launcher/bridge"
+	"github.com/nokka/slashdiablo-launcher/cli"
 	ladderClient "github.com/nokka/slashdiablo-launcher/clients/ladder"
 	"github.com/nokka/slashdiablo-launcher/clients/slashdiablo"
 	"github.com/nokka/slashdiablo-launcher/config"
@@ -35,30 +37,12 @@ func main() {
 	core.QCoreApplication_SetOrganizationDomain("slashdiablo.net")
 	core.QCoreApplication_SetApplicationVersion("1.0.0")
 
-	// Enable high dpi scaling, useful for devices with high pixel density displays.
-	core.QCoreApplication_SetAttribute(core.Qt__AA_EnableHighDpiScaling, true)
-
-	// Create base application.
-	app := widgets.NewQApplication(len(os.Args), os.Args)
-
-	// Create new frameless window.
-	fw := goqmlframeless.NewWindow(goqmlframeless.Options{
-		Width:  1024,
-		Height: 600,
-		Alpha:  1.0,
-		Color:  goqmlframeless.RGB{R: 3, G: 2, B: 2},
-	})
-
-	// QML Widget that will be used to draw on.
-	qmlWidget := quick.NewQQuickWidget(nil)
-	qmlWidget.SetResizeMode(quick.QQuickWidget__SizeRootObjectToView)
-
-	// Add QML widget to layout.
-	fw.Layout.AddWidget(qmlWidget, 0, 0)
+	// Run headless when a command was given, without creating any windows.
+	headless := len(os.Args) > 1 && cli.IsCommand(os.Args[1])
 
 	configPath, err := getConfigPath()
 	if err != nil {
-		os.Exit(0)
+		exit(headless, err)
 	}
 
 	// Data directory is a requirement for the app.
@@ -67,46 +51,87 @@ func main() {
 	// Setup file logger.
 	logger := log.NewLogger(configPath)
 
-	// Enable debugger if it was enabled through the env variable.
-	if debugMode {
-		enableDebugger(logger)
-	}
-
 	// Setup local storage.
 	store := storage.NewStore(configPath)
 	if err := store.Load(); err != nil {
-		logger.Error(errors.New("unable to load config"))
-		os.Exit(0)
+		logger.Error(fmt.Errorf("unable to load config: %s", err))
+		exit(headless, err)
 	}
 
 	conf, err := store.Read()
 	if err != nil {
-		logger.Error(errors.New("unable to read config"))
-		os.Exit(0)
+		logger.Error(fmt.Errorf("unable to read config: %s", err))
+		exit(headless, err)
 	}
 
 	// Models.
 	lm := ladder.NewTopLadderModel(nil)
 	gm := config.NewGameModel(nil)
+	gwm := config.NewGatewayModel(nil)
+	fm := config.NewFlagModel(nil)
 	nm := news.NewModel(nil)
+	vm := d2.NewValidationModel(nil)
+	im := d2.NewInstanceModel(nil)
 
 	// Setup clients.
-	sc := slashdiablo.NewClient()
-	lc := ladderClient.NewClient()
+	sc := slashdiablo.NewClient(
+		envString("PATCH_ADDRESS", configString(conf.PatchAddress, slashdiablo.DefaultAddress)),
+	)
+	lc := ladderClient.NewClient(
+		envString("LADDER_ADDRESS", configString(conf.LadderAddress, ladderClient.DefaultAddress)),
+	)
 
 	// Setup services.
-	cs := config.NewService(store, gm)
-	d2s := d2.NewService(sc, cs, logger)
+	cs := config.NewService(store, gm, gwm)
+	d2s := d2.NewService(sc, cs, logger, configPath)
 	ls := ladder.NewService(lc, lm)
 	ns := news.NewService(sc, nm)
 
+	// Roll back patches interrupted by a crash, before the game files are used.
+	if err := d2s.RecoverPatches(); err != nil {
+		logger.Error(err)
+	}
+
+	if headless {
+		runner := cli.NewRunner(d2s, cs, os.Stdout, os.Stderr)
+		os.Exit(runner.Run(os.Args[1:]))
+	}
+
+	// Enable high dpi scaling, useful for devices with high pixel density displays.
+	core.QCoreApplication_SetAttribute(core.Qt__AA_EnableHighDpiScaling, true)
+
+	// Create base application.
+	app := widgets.NewQApplication(len(os.Args), os.Args)
+
+	// Create new frameless window.
+	fw := goqmlframeless.NewWindow(goqmlframeless.Options{
+		Width:  1024,
+		Height: 600,
+		Alpha:  1.0,
+		Color:  goqmlframeless.RGB{R: 3, G: 2, B: 2},
+	})
+
+	// QML Widget that will be used to draw on.
+	qmlWidget := quick.NewQQuickWidget(nil)
+	qmlWidget.SetResizeMode(quick.QQuickWidget__SizeRootObjectToView)
+
+	// Add QML widget to layout.
+	fw.Layout.AddWidget(qmlWidget, 0, 0)
+
+	// Enable debugger if it was enabled through the env variable.
+	if debugMode {
+		enableDebugger(logger)
+	}
+
 	// Populate the game model with the game config
 	// before passing it to the config bridge.
 	populateGameModel(conf, gm)
+	populateGatewayModel(conf, gwm)
+	populateFlagModel(fm)
 
 	// Setup QML bridges with all dependencies.
-	diabloBridge := bridge.NewDiablo(d2s, conf.Gateway, logger)
-	configBridge := bridge.NewConfig(cs, gm, logger)
+	diabloBridge := bridge.NewDiablo(d2s, vm, im, conf.Gateway, logger)
+	configBridge := bridge.NewConfig(cs, gm, gwm, fm, logger)
 	ladderBridge := bridge.NewLadder(ls, lm, logger)
 	newsBridge := bridge.NewNews(ns, nm, logger)
 
@@ -156,19 +181,61 @@ func getConfigPath() (string, error) {
 
 func populateGameModel(conf *storage.Config, gm *config.GameModel) {
 	for _, game := range conf.Games {
-		g := config.NewGame(nil)
-		g.ID = game.ID
-		g.Location = game.Location
-		g.Instances = game.Instances
-		g.Maphack = game.Maphack
-		g.OverrideBHCfg = game.OverrideBHCfg
-		g.HD = game.HD
-		g.Flags = game.Flags
+		gm.AddGame(config.GameFromStorage(game))
+	}
+}
+
+func populateGatewayModel(conf *storage.Config, gwm *config.GatewayModel) {
+	for _, name := range d2.BuiltinGateways {
+		g := config.NewGateway(nil)
+		g.Name = name
+
+		gwm.AddGateway(g)
+	}
+
+	for _, gateway := range conf.Gateways {
+		g := config.NewGateway(nil)
+		g.Name = gateway.Name
+		g.Host = gateway.Host
+		g.Timezone = gateway.Timezone
+		g.Custom = true
+
+		gwm.AddGateway(g)
+	}
+}
+
+func populateFlagModel(fm *config.FlagModel) {
+	for _, flag := range config.KnownFlags {
+		f := config.NewFlag(nil)
+		f.Name = flag.Name
+		f.Description = flag.Description
+		f.Argument = flag.Argument
 
-		gm.AddGame(g)
+		fm.AddFlag(f)
 	}
 }
 
+// exit will terminate the app after a startup error, headless runs report
+// the error on stderr and the launcher shows it in a dialog.
+func exit(headless bool, err error) {
+	if headless {
+		fmt.Fprintf(os.Stderr, "startup failed: %s\n", err)
+		os.Exit(cli.ExitError)
+	}
+
+	// The window hasn't been created yet, show the error on its own.
+	widgets.NewQApplication(len(os.Args), os.Args)
+	widgets.QMessageBox_Critical(
+		nil,
+		"Slashdiablo launcher",
+		fmt.Sprintf("The launcher couldn't start: %s", err),
+		widgets.QMessageBox__Ok,
+		widgets.QMessageBox__Ok,
+	)
+
+	os.Exit(cli.ExitError)
+}
+
 // enableDebugger will capture stdout and stderr output.
 func enableDebugger(logger log.Logger) {
 	r, w, err := os.Pipe()
@@ -197,6 +264,14 @@ func envString(env, fallback string) string {
 	return e
 }
 
+// configString returns the configured value, or the fallback if it wasn't configured.
+func configString(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
+
 // envBool extracts a bool from os environment.
 func envBool(env string, fallback bool) bool {
 	e := os.Getenv(env)
@@ -1,6 +1,7 @@
 package news
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
@@ -29,11 +30,17 @@ type JSONItem struct {
 
 // SetNewsItems will fetch the news from the Slashdiablo server.
 func (s *service) SetNewsItems() error {
-	contents, err := s.client.GetNews()
+	contents, err := s.client.GetNews(context.Background())
 	if err != nil {
+		// No news has been published, which isn't an error.
+		if slashdiablo.IsNotFound(err) {
+			return nil
+		}
 		return err
 	}
 
+	defer contents.Close()
+
 	bytes, err := ioutil.ReadAll(contents)
 	if err != nil {
 		return err
@@ -38,5 +38,7 @@ ComboBox {
         }
     }
 
-    delegate: DropdownDelegate{}
+    delegate: DropdownDelegate{
+        textRole: dropdown.textRole
+    }
 }
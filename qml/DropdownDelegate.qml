@@ -2,12 +2,15 @@ import QtQuick 2.12
 import QtQuick.Controls 2.5
 
 ItemDelegate {
+    // Set when the dropdown model has roles, the role shown as text.
+    property string textRole: ""
+
     width: parent.width
     height: 30
 
     contentItem: Title {
         id:textItem
-        text: modelData
+        text: (textRole ? model[textRole] : modelData)
         color: hovered ? "#ffffff" : "#57555e"
         verticalAlignment: Text.AlignVCenter
         horizontalAlignment: Text.AlignLeft
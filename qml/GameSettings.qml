@@ -5,8 +5,12 @@ import QtQuick.Dialogs 1.3      // FileDialog
 
 Item {
     property var game: {}
+
+    // Launch delays in milliseconds, 0 is the default delay.
+    property var launchDelays: [0, 1000, 2000, 3000, 5000, 10000]
     property bool depApplied: false
     property bool depError: false
+    property bool flagsInvalid: false
 
     function setGame(current) {
         // Set current game instance to the view.
@@ -25,34 +29,65 @@ Item {
     }
 
     function updateToggleBoxes(current) {
-        if(current.flags != null) {
-            windowModeFlag.active = current.flags.includes("-w")
-            gfxFlag.active = current.flags.includes("-3dfx")
-            skipFlag.active = current.flags.includes("-skiptobnet")
-        } else {
-            windowModeFlag.active = false
-            gfxFlag.active = false
-            skipFlag.active = false
+        for(var i = 0; i < flagToggles.count; i++) {
+            var toggle = flagToggles.itemAt(i)
+            toggle.active = (toggle.toggleable && current.flags != null && current.flags.includes(toggle.label))
         }
     }
 
     function makeFlagList() {
-        var flags = []
-        if(windowModeFlag.active) {
-            flags.push("-w")
+        var toggles = []
+        for(var i = 0; i < flagToggles.count; i++) {
+            if(flagToggles.itemAt(i).toggleable) {
+                toggles.push(flagToggles.itemAt(i).label)
+            }
         }
-        
-        if(gfxFlag.active) {
-            flags.push("-3dfx")
+
+        // Keep the flags that can't be toggled, such as flags with arguments.
+        var flags = []
+        if(game != undefined && game.flags != undefined) {
+            for(var j = 0; j < game.flags.length; j++) {
+                if(!toggles.includes(game.flags[j])) {
+                    flags.push(game.flags[j])
+                }
+            }
         }
 
-        if(skipFlag.active) {
-            flags.push("-skiptobnet")
+        for(var k = 0; k < flagToggles.count; k++) {
+            if(flagToggles.itemAt(k).toggleable && flagToggles.itemAt(k).active) {
+                flags.push(flagToggles.itemAt(k).label)
+            }
         }
 
         return flags
     }
 
+    // profile returns the profile of the instance at the index, if it has one.
+    function profile(index) {
+        if(game != undefined && game.profiles != undefined && index < game.profiles.length) {
+            return game.profiles[index]
+        }
+
+        return null
+    }
+
+    // makeProfileList returns the profiles of the instances, without flags
+    // of their own an instance uses the flags of the game.
+    function makeProfileList() {
+        var profiles = []
+        for(var i = 0; i < instanceProfiles.count; i++) {
+            var item = instanceProfiles.itemAt(i)
+            var flags = item.flagsText.trim()
+
+            profiles.push({
+                label: item.labelText.trim(),
+                flags: (flags.length > 0 ? flags.split(/\s+/) : null)
+            })
+        }
+
+        return profiles
+    }
+
     function updateGameModel() {
         if(game != undefined) {
             var body = {
@@ -62,20 +97,29 @@ Item {
                 maphack: maphackSwitch.checked,
                 override_bh_cfg: overrideMaphackCfgSwitch.checked,
                 hd: hdSwitch.checked,
-                flags: makeFlagList()
+                flags: makeFlagList(),
+                profiles: makeProfileList(),
+                launch_delay: launchDelays[gameLaunchDelay.currentIndex],
+                allow_unknown_flags: (game.allow_unknown_flags == true)
             }
             
-            settings.upsertGame(JSON.stringify(body))
+            // Unknown or malformed flags aren't saved.
+            flagsInvalid = !settings.upsertGame(JSON.stringify(body))
         }
     }
 
-    Item {
+    Flickable {
         id: currentGame
         width: parent.width * 0.95
-        height: 400
+        height: parent.height
+        contentHeight: settingsLayout.implicitHeight
+        boundsBehavior: Flickable.StopAtBounds
+        clip: true
 
         anchors.horizontalCenter: parent.horizontalCenter
 
+        ScrollBar.vertical: ScrollBar {}
+
         ColumnLayout {
             id: settingsLayout
             width: (currentGame.width * 0.95)
@@ -112,7 +156,7 @@ Item {
 
                     TextField {
                         id: d2pathInput
-                        width: fileDialogBox.width * 0.55; height: 35
+                        width: fileDialogBox.width * 0.80; height: 35
                         font.pixelSize: 11
                         color: "#454545"
                         readOnly: true
@@ -128,45 +172,12 @@ Item {
                         label: "Open"
                         borderRadius: 0
                         borderColor: "#373737"
-                        width: fileDialogBox.width * 0.15; height: 35
+                        width: fileDialogBox.width * 0.20; height: 35
                         cursorShape: Qt.PointingHandCursor
 
                         onClicked: d2PathDialog.open()
                     }
 
-                    Item {
-                        width: (fileDialogBox.width - (d2pathInput.width + chooseD2Path.width)); height: 35
-
-                        Row {
-                            spacing: 2
-                            leftPadding: 2
-
-                            ToggleButton {
-                                id: windowModeFlag
-                                label: "-w"
-                                width: 47
-                                height: 35
-                                onClicked: updateGameModel()
-                            }
-
-                            ToggleButton {
-                                id: gfxFlag
-                                label: "-3dfx"
-                                width: 47
-                                height: 35
-                                onClicked: updateGameModel()
-                            }
-
-                            ToggleButton {
-                                id: skipFlag
-                                label: "-skip"
-                                width: 47
-                                height: 35
-                                onClicked: updateGameModel()
-                            }
-                        }
-                    }
-
                     // File dialog.
                     FileDialog {
                         id: d2PathDialog
@@ -187,6 +198,56 @@ Item {
                 Separator{}
             }
 
+            // Launch flags box.
+            Item {
+                Layout.preferredWidth: settingsLayout.width
+                Layout.preferredHeight: (flagsFlow.height + 45)
+
+                Column {
+                    topPadding: 10
+                    spacing: 5
+
+                    Title {
+                        text: "LAUNCH FLAGS"
+                        font.pixelSize: 13
+                    }
+
+                    SText {
+                        text: (flagsInvalid ? "Unknown or malformed launch flags weren't saved." : "Command line flags this install is launched with.")
+                        font.pixelSize: 11
+                        color: (flagsInvalid ? "#8f3131" : "#454545")
+                    }
+
+                    Flow {
+                        id: flagsFlow
+                        width: settingsLayout.width
+                        spacing: 2
+
+                        // Only flags without arguments can be toggled.
+                        Repeater {
+                            id: flagToggles
+                            model: settings.knownFlags
+
+                            delegate: ToggleButton {
+                                property bool toggleable: (model.argument == "none")
+
+                                visible: toggleable
+                                label: model.name
+                                width: 80
+                                height: 30
+
+                                ToolTip.visible: hovering
+                                ToolTip.text: model.description
+
+                                onClicked: updateGameModel()
+                            }
+                        }
+                    }
+                }
+
+                Separator{}
+            }
+
             // Game instances box.
             Item {
                 Layout.preferredWidth: settingsLayout.width
@@ -228,6 +289,109 @@ Item {
                 Separator{}
             }
 
+            // Instance profiles box.
+            Item {
+                Layout.preferredWidth: settingsLayout.width
+                Layout.preferredHeight: (instanceProfiles.count * 32) + 10
+
+                Column {
+                    topPadding: 5
+                    spacing: 2
+
+                    Repeater {
+                        id: instanceProfiles
+                        model: (gameInstances.currentIndex+1)
+
+                        delegate: Row {
+                            property alias labelText: profileLabel.text
+                            property alias flagsText: profileFlags.text
+
+                            spacing: 2
+
+                            SText {
+                                width: 30; height: 30
+                                text: "#" + (index+1)
+                                font.pixelSize: 11
+                                verticalAlignment: Text.AlignVCenter
+                            }
+
+                            TextField {
+                                id: profileLabel
+                                width: settingsLayout.width * 0.35; height: 30
+                                font.pixelSize: 11
+                                color: "#454545"
+                                placeholderText: "Label, e.g. MF sorc"
+                                text: (profile(index) != null ? profile(index).label : "")
+
+                                background: Rectangle {
+                                    color: "#1a1a17"
+                                }
+
+                                onEditingFinished: updateGameModel()
+                            }
+
+                            TextField {
+                                id: profileFlags
+                                width: settingsLayout.width * 0.55; height: 30
+                                font.pixelSize: 11
+                                color: "#454545"
+                                placeholderText: "Flags, the flags of the install if empty"
+                                text: ((profile(index) != null && profile(index).flags != null) ? profile(index).flags.join(" ") : "")
+
+                                background: Rectangle {
+                                    color: "#1a1a17"
+                                }
+
+                                onEditingFinished: updateGameModel()
+                            }
+                        }
+                    }
+                }
+
+                Separator{}
+            }
+
+            // Launch delay box.
+            Item {
+                Layout.preferredWidth: settingsLayout.width
+                Layout.preferredHeight: 60
+
+                Row {
+                    topPadding: 10
+
+                    Column {
+                        width: (settingsLayout.width - launchDelayDropdown.width)
+
+                        Title {
+                            text: "DELAY BETWEEN INSTANCES"
+                            font.pixelSize: 13
+                        }
+
+                        SText {
+                            text: "Least time to wait before launching the next instance, if it doesn't start properly."
+                            font.pixelSize: 11
+                            topPadding: 5
+                            color: "#454545"
+                        }
+                    }
+                    Column {
+                        id: launchDelayDropdown
+                        width: 100
+                        Dropdown{
+                            id: gameLaunchDelay
+                            currentIndex: ((game != undefined && game.launch_delay != undefined) ? Math.max(launchDelays.indexOf(game.launch_delay), 0) : 0)
+                            model: [ "Default", "1s", "2s", "3s", "5s", "10s" ]
+                            height: 30
+                            width: 100
+
+                            onActivated: updateGameModel()
+                        }
+                    }
+                }
+
+                Separator{}
+            }
+
             // Include maphack box.
             Item {
                 Layout.preferredWidth: settingsLayout.width
@@ -0,0 +1,157 @@
+import QtQuick 2.12
+import QtQuick.Controls 2.5     // Popup, TextField
+import QtQuick.Dialogs 1.3      // FileDialog
+
+// Imports a launcher setup exported on another machine.
+Popup {
+    id: importPopup
+    width: 500
+    height: 250
+    modal: true
+    focus: true
+    padding: 20
+
+    background: Rectangle {
+        color: "#0f0f0f"
+        border.color: "#1e1b26"
+        border.width: 1
+    }
+
+    onOpened: {
+        importPath.text = ""
+        remapFrom.text = ""
+        remapTo.text = ""
+        settings.importError = ""
+    }
+
+    function importSetup() {
+        var remap = {}
+        if(remapFrom.text.trim().length > 0) {
+            remap[remapFrom.text.trim()] = remapTo.text.trim()
+        }
+
+        var body = {
+            path: importPath.text,
+            remap: remap
+        }
+
+        if(settings.importConfig(JSON.stringify(body))) {
+            importPopup.close()
+        }
+    }
+
+    Column {
+        width: parent.width
+        spacing: 8
+
+        Title {
+            text: "IMPORT SETUP"
+            font.pixelSize: 13
+        }
+
+        SText {
+            text: "Replaces the games and gateway of the profile with an exported setup."
+            font.pixelSize: 11
+            color: "#454545"
+        }
+
+        Row {
+            spacing: 2
+
+            TextField {
+                id: importPath
+                width: 370; height: 30
+                font.pixelSize: 11
+                color: "#454545"
+                readOnly: true
+                placeholderText: "Exported setup"
+
+                background: Rectangle {
+                    color: "#1a1a17"
+                }
+            }
+
+            SButton {
+                label: "Open"
+                borderRadius: 0
+                borderColor: "#373737"
+                width: 88; height: 30
+                cursorShape: Qt.PointingHandCursor
+
+                onClicked: importDialog.open()
+            }
+        }
+
+        // Games are kept somewhere else on this machine.
+        Row {
+            spacing: 2
+
+            TextField {
+                id: remapFrom
+                width: 229; height: 30
+                font.pixelSize: 11
+                color: "#454545"
+                placeholderText: "Games directory in the setup, e.g. C:\\Games"
+
+                background: Rectangle {
+                    color: "#1a1a17"
+                }
+            }
+
+            TextField {
+                id: remapTo
+                width: 229; height: 30
+                font.pixelSize: 11
+                color: "#454545"
+                placeholderText: "Games directory on this machine"
+
+                background: Rectangle {
+                    color: "#1a1a17"
+                }
+            }
+        }
+
+        SText {
+            width: parent.width
+            text: settings.importError
+            visible: (settings.importError !== "")
+            font.pixelSize: 11
+            color: "#8f3131"
+            wrapMode: Text.WordWrap
+        }
+
+        Row {
+            spacing: 5
+
+            PlainButton {
+                width: 100
+                height: 35
+                label: "IMPORT"
+                fontSize: 11
+                enabled: (importPath.text !== "")
+
+                onClicked: importSetup()
+            }
+
+            PlainButton {
+                width: 100
+                height: 35
+                label: "CANCEL"
+                fontSize: 11
+
+                onClicked: importPopup.close()
+            }
+        }
+    }
+
+    FileDialog {
+        id: importDialog
+        nameFilters: ["Launcher setup (*.json)"]
+        folder: shortcuts.home
+
+        onAccepted: {
+            var path = importDialog.fileUrl.toString()
+            importPath.text = path.replace(/^(file:\/{2})/,"")
+        }
+    }
+}
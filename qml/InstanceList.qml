@@ -0,0 +1,66 @@
+import QtQuick 2.12
+
+// Lists the running game instances, each of them can be stopped.
+Item {
+    id: instanceList
+    height: (instances.count * 30) + 35
+    visible: (instances.count > 0 || diablo.lastCrash !== "")
+
+    ListView {
+        id: instances
+        width: parent.width
+        height: (count * 30)
+        anchors.top: parent.top
+        interactive: false
+        model: diablo.instances
+
+        delegate: Item {
+            width: parent.width
+            height: 30
+
+            SText {
+                anchors.left: parent.left
+                anchors.verticalCenter: parent.verticalCenter
+                text: (model.label != "" ? model.label : "Diablo II") + " (" + model.pid + ") started " + model.startedAt
+                font.pixelSize: 12
+            }
+
+            PlainButton {
+                width: 60
+                height: 22
+                label: "STOP"
+                fontSize: 10
+                anchors.right: parent.right
+                anchors.verticalCenter: parent.verticalCenter
+
+                onClicked: diablo.stopInstance(model.pid)
+            }
+        }
+    }
+
+    // The latest crash of the session, every crash is in the log.
+    SText {
+        width: parent.width - 90
+        anchors.left: parent.left
+        anchors.verticalCenter: stopAll.verticalCenter
+        text: diablo.lastCrash
+        visible: (diablo.lastCrash !== "")
+        font.pixelSize: 11
+        color: "#8f3131"
+        elide: Text.ElideRight
+    }
+
+    PlainButton {
+        id: stopAll
+        width: 80
+        height: 25
+        label: "STOP ALL"
+        fontSize: 10
+        visible: (instances.count > 0)
+        anchors.top: instances.bottom
+        anchors.topMargin: 5
+        anchors.right: parent.right
+
+        onClicked: diablo.stopAll()
+    }
+}
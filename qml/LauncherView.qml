@@ -54,6 +54,15 @@ Item {
         }
     }
         
+    // Running game instances, above the bottom bar.
+    InstanceList {
+        width: 400
+        anchors.left: parent.left
+        anchors.leftMargin: 20
+        anchors.bottom: bottombar.top
+        anchors.bottomMargin: 10
+    }
+
     // Bottom bar.
     Item {
         id: bottombar
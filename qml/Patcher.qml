@@ -66,6 +66,17 @@ Item {
             text: diablo.status
             font.pixelSize: 12
         }
+
+        PlainButton {
+            width: 80
+            height: 25
+            label: "CANCEL"
+            fontSize: 10
+            anchors.bottom: parent.bottom
+            anchors.right: parent.right
+
+            onClicked: diablo.cancelPatch()
+        }
     }
 
     // Show when patcher errors.
@@ -87,7 +98,7 @@ Item {
             id: patchError
             anchors.left: parent.left
             anchors.verticalCenter: parent.verticalCenter
-            text: "Couldn't patch game files"
+            text: diablo.status
             font.pixelSize: 15
             anchors.leftMargin: 30
             topPadding: 5
@@ -131,8 +142,9 @@ Item {
                 anchors.horizontalCenter: parent.horizontalCenter
                 anchors.bottom: playButton.top
                 anchors.bottomMargin: 5
-                currentIndex: (diablo.gateway == "Slashdiablo" ? 0 : 1)
-                model: ["Slashdiablo", "Battle.net"]
+                currentIndex: settings.gateways.indexOf(diablo.gateway)
+                model: settings.gateways
+                textRole: "name"
                 height: 30
                 width: 275
 
@@ -147,6 +159,7 @@ Item {
                 label: "PLAY"
                 fontSize: 15
                 width: 275; height: 50
+                enabled: !diablo.launching
                 backgroundColor: "#5c0202"
                 colorHovered: "#3b0000"
                 anchors.verticalCenter: parent.verticalCenter
@@ -154,6 +167,16 @@ Item {
 
                 onClicked: diablo.launchGame()
             }
+
+            // Launch progress of the instances.
+            SText {
+                anchors.top: playButton.bottom
+                anchors.topMargin: 5
+                anchors.horizontalCenter: parent.horizontalCenter
+                text: diablo.launchStatus
+                font.pixelSize: 11
+                visible: (diablo.launchStatus !== "")
+            }
         }
     }
 
@@ -195,6 +218,22 @@ Item {
         }
     }
 
+    // Every profile has its own gateway, and games that might need patching.
+    Connections {
+        target: settings
+        onProfileSwitched: gamesReplaced(gateway)
+        onConfigImported: gamesReplaced(gateway)
+    }
+
+    // gamesReplaced will set the gateway and check the games, after the games have been replaced.
+    function gamesReplaced(gateway) {
+        diablo.updateGateway(gateway === "" ? "Slashdiablo" : gateway)
+
+        if(settings.games.rowCount() > 0) {
+            diablo.validateVersion()
+        }
+    }
+
     Component.onCompleted: {
         // Set default gateway if it hasn't been set.
         if(diablo.gateway === "") {
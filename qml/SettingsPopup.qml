@@ -1,6 +1,7 @@
 import QtQuick 2.4
 import QtQuick.Controls 2.5
 import QtQuick.Layouts 1.3
+import QtQuick.Dialogs 1.3      // FileDialog
 
 Popup {
     id: settingsPopup
@@ -17,13 +18,16 @@ Popup {
         "maphack": 264,
         "override_bh_cfg": 272,
         "hd": 288,
-        "flags": 320
+        "flags": 320,
+        "launch_delay": 384,
+        "profiles": 512,
+        "allow_unknown_flags": 1024
     }
 
     modal: true
     focus: true
     width: 850
-    height: 500
+    height: 560
     margins: 0
     padding: 0
     
@@ -102,7 +106,10 @@ Popup {
                                 "maphack": model.data(model.index(this.currentIndex, 0), gameRoles.maphack),
                                 "override_bh_cfg": model.data(model.index(this.currentIndex, 0), gameRoles.override_bh_cfg),
                                 "hd": model.data(model.index(this.currentIndex, 0), gameRoles.hd),
-                                "flags": model.data(model.index(this.currentIndex, 0), gameRoles.flags)
+                                "flags": model.data(model.index(this.currentIndex, 0), gameRoles.flags),
+                                "launch_delay": model.data(model.index(this.currentIndex, 0), gameRoles.launch_delay),
+                                "profiles": model.data(model.index(this.currentIndex, 0), gameRoles.profiles),
+                                "allow_unknown_flags": model.data(model.index(this.currentIndex, 0), gameRoles.allow_unknown_flags)
                             })
                         }
                     }
@@ -348,6 +355,8 @@ Popup {
                         anchors.left: parent.left
                         anchors.top: parent.top
                         anchors.topMargin: 45
+                        anchors.bottom: parent.bottom
+                        anchors.bottomMargin: 10
                         anchors.horizontalCenter: parent.horizontalCenter
                     }
                 }
@@ -413,6 +422,55 @@ Popup {
         }
     }
 
+    // Move the setup between machines.
+    Row {
+        anchors.bottom: parent.bottom
+        anchors.left: parent.left
+        anchors.bottomMargin: 20
+        anchors.leftMargin: 30
+        spacing: 20
+
+        Title {
+            text: "EXPORT SETUP"
+            font.pixelSize: 11
+            visible: (gamesList.count > 0)
+
+            MouseArea {
+                anchors.fill: parent
+                cursorShape: Qt.PointingHandCursor
+                onClicked: exportDialog.open()
+            }
+        }
+
+        Title {
+            text: "IMPORT SETUP"
+            font.pixelSize: 11
+
+            MouseArea {
+                anchors.fill: parent
+                cursorShape: Qt.PointingHandCursor
+                onClicked: importPopup.open()
+            }
+        }
+    }
+
+    FileDialog {
+        id: exportDialog
+        selectExisting: false
+        nameFilters: ["Launcher setup (*.json)"]
+        folder: shortcuts.home
+
+        onAccepted: {
+            var path = exportDialog.fileUrl.toString()
+            settings.exportConfig(path.replace(/^(file:\/{2})/,""))
+        }
+    }
+
+    ImportPopup {
+        id: importPopup
+        anchors.centerIn: parent
+    }
+
     // validateGames will validate that the input is correctly set.
     function validateGames() {
        for(var i = 0; i < gamesList.count; i++) {
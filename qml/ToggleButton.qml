@@ -8,6 +8,7 @@ Button {
     property int fontSize: 11
     property string label: ""
     property alias cursorShape: mouseArea.cursorShape
+    property alias hovering: mouseArea.containsMouse
     
     Text {
         text: label
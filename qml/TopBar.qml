@@ -1,5 +1,6 @@
 import QtQuick 2.12
 import QtQuick.Layouts 1.3
+import QtQuick.Controls 2.5     // Popup, TextField
 
 Item {
     id: topbar
@@ -83,8 +84,94 @@ Item {
         }
     }
 
+    // Profiles, the last item adds a new profile.
+    Dropdown {
+        id: profiles
+        width: 180
+        height: 30
+        anchors.right: settingsItem.right
+        anchors.rightMargin: 30
+        anchors.verticalCenter: parent.verticalCenter
+        model: settings.profileNames.concat(["+ New profile"])
+        currentIndex: settings.profileNames.indexOf(settings.activeProfile)
+
+        onActivated: {
+            if(index == (count-1)) {
+                newProfilePopup.open()
+            } else {
+                settings.switchProfile(currentText)
+            }
+
+            // Show the active profile, even if switching failed.
+            currentIndex = Qt.binding(function() { return settings.profileNames.indexOf(settings.activeProfile) })
+        }
+    }
+
+    Popup {
+        id: newProfilePopup
+        width: 300
+        height: 90
+        x: (profiles.x + profiles.width - width)
+        y: (profiles.y + profiles.height + 5)
+        focus: true
+
+        background: Rectangle {
+            color: "#0f0f0f"
+            border.color: "#1e1b26"
+            border.width: 1
+        }
+
+        onOpened: {
+            profileName.text = ""
+            profileName.forceActiveFocus()
+        }
+
+        Column {
+            spacing: 5
+
+            SText {
+                text: "NEW PROFILE"
+                font.pixelSize: 11
+            }
+
+            Row {
+                spacing: 2
+
+                TextField {
+                    id: profileName
+                    width: 200; height: 35
+                    font.pixelSize: 11
+                    color: "#454545"
+                    placeholderText: "Name, e.g. Ladder season"
+
+                    background: Rectangle {
+                        color: "#1a1a17"
+                    }
+
+                    onAccepted: addProfileButton.clicked()
+                }
+
+                PlainButton {
+                    id: addProfileButton
+                    width: 75
+                    height: 35
+                    label: "ADD"
+                    fontSize: 10
+
+                    onClicked: {
+                        if(settings.addProfile(profileName.text)) {
+                            settings.switchProfile(profileName.text.trim())
+                            newProfilePopup.close()
+                        }
+                    }
+                }
+            }
+        }
+    }
+
     // Settings.
     Item {
+        id: settingsItem
         width: 120; height: parent.height
         anchors.right: parent.right
         anchors.rightMargin: 20
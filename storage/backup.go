@@ -0,0 +1,116 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+)
+
+const (
+	// maxBackups is the number of previous configs kept, the newest is backup 1.
+	maxBackups = 3
+
+	// brokenSuffix is appended to a config that couldn't be read, when it's replaced by a backup.
+	brokenSuffix = ".broken"
+)
+
+// ErrNoValidConfig is returned when neither the config nor any of its backups can be read.
+var ErrNoValidConfig = fmt.Errorf("%s can't be read and there's no backup to recover it from", configName)
+
+// rotateBackups will back up the current config as the newest backup, pushing out the oldest.
+func (s *store) rotateBackups() error {
+	body, err := ioutil.ReadFile(s.configPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	return s.backUp(body)
+}
+
+// backUp will add the config body as the newest backup, pushing out the oldest. Configs
+// that can't be read aren't backed up, so the backups are always known to be good.
+func (s *store) backUp(body []byte) error {
+	if !isValidConfig(body) {
+		return nil
+	}
+
+	// Nothing to gain from a backup identical to the newest one.
+	newest, err := ioutil.ReadFile(s.backupPath(1))
+	if err == nil && string(newest) == string(body) {
+		return nil
+	}
+
+	for i := maxBackups - 1; i >= 1; i-- {
+		if err := os.Rename(s.backupPath(i), s.backupPath(i+1)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	return writeFile(s.backupPath(1), body)
+}
+
+// recover will replace a config that can't be read with the newest backup that can,
+// the broken config is kept next to it.
+func (s *store) recover() error {
+	body, err := ioutil.ReadFile(s.configPath())
+	if err != nil {
+		return err
+	}
+
+	// Configs written by a newer launcher aren't broken, loading reports that they can't be migrated.
+	if isValidConfig(body) || isNewerConfig(body) {
+		return nil
+	}
+
+	for i := 1; i <= maxBackups; i++ {
+		backup, err := ioutil.ReadFile(s.backupPath(i))
+		if err != nil || !isValidConfig(backup) {
+			continue
+		}
+
+		// Renaming can't replace files on every OS, remove the previous broken config first.
+		broken := s.configPath() + brokenSuffix
+		if err := os.Remove(broken); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+
+		if err := os.Rename(s.configPath(), broken); err != nil {
+			return err
+		}
+
+		return writeFile(s.configPath(), backup)
+	}
+
+	return ErrNoValidConfig
+}
+
+// backupPath returns the path of the backup with the number, 1 being the newest.
+func (s *store) backupPath(number int) string {
+	return fmt.Sprintf("%s.%d.bak", s.configPath(), number)
+}
+
+// isValidConfig reports if the body can be read as a config, the way it's read once it's loaded.
+func isValidConfig(body []byte) bool {
+	migrated, _, err := migrate(body)
+	if err != nil {
+		return false
+	}
+
+	var conf Config
+	return json.Unmarshal(migrated, &conf) == nil
+}
+
+// isNewerConfig reports if the body is a config of a newer version than this launcher supports.
+func isNewerConfig(body []byte) bool {
+	var raw map[string]interface{}
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return false
+	}
+
+	version, err := configVersion(raw)
+	return err == nil && version > CurrentVersion
+}
@@ -0,0 +1,126 @@
+package storage
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRecover(t *testing.T) {
+	const (
+		valid     = `{"version":2,"games":[],"gateway":"Slashdiablo","active_profile":"Default"}`
+		older     = `{"version":2,"games":[],"gateway":"Battle.net","active_profile":"Default"}`
+		truncated = `{"version":2,"games":[{"id":"a",`
+	)
+
+	tests := []struct {
+		name    string
+		config  string
+		backups []string
+		broken  string
+		gateway string
+		err     error
+	}{
+		{
+			name:    "valid config",
+			config:  valid,
+			backups: []string{older},
+			gateway: "Slashdiablo",
+		},
+		{
+			name:    "truncated config",
+			config:  truncated,
+			backups: []string{valid, older},
+			gateway: "Slashdiablo",
+		},
+		{
+			name:    "truncated config and newest backup",
+			config:  truncated,
+			backups: []string{truncated, older},
+			gateway: "Battle.net",
+		},
+		{
+			name:    "null config",
+			config:  `null`,
+			backups: []string{valid},
+			gateway: "Slashdiablo",
+		},
+		{
+			name:    "existing broken config",
+			config:  truncated,
+			backups: []string{valid},
+			broken:  `{"version":`,
+			gateway: "Slashdiablo",
+		},
+		{
+			name:    "no valid backup",
+			config:  truncated,
+			backups: []string{truncated},
+			err:     ErrNoValidConfig,
+		},
+	}
+
+	for _, tt := range tests {
+		dir, err := ioutil.TempDir("", "storage")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+
+		s := NewStore(dir).(*store)
+
+		if err := ioutil.WriteFile(s.configPath(), []byte(tt.config), filePermissions); err != nil {
+			t.Fatal(err)
+		}
+
+		for i, backup := range tt.backups {
+			if err := ioutil.WriteFile(s.backupPath(i+1), []byte(backup), filePermissions); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		broken := filepath.Join(dir, configName+brokenSuffix)
+		if tt.broken != "" {
+			if err := ioutil.WriteFile(broken, []byte(tt.broken), filePermissions); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		err = s.Load()
+		if err != tt.err {
+			t.Errorf("%s: got error %v, want %v", tt.name, err, tt.err)
+			continue
+		}
+
+		if tt.err != nil {
+			continue
+		}
+
+		conf, err := s.Read()
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %s", tt.name, err)
+		}
+
+		if conf.Gateway != tt.gateway {
+			t.Errorf("%s: got gateway %s, want %s", tt.name, conf.Gateway, tt.gateway)
+		}
+
+		// A recovered config is kept as the broken config, replacing the previous one.
+		body, err := ioutil.ReadFile(broken)
+		if tt.config == valid {
+			if !os.IsNotExist(err) {
+				t.Errorf("%s: valid config was kept as broken", tt.name)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %s", tt.name, err)
+		}
+
+		if string(body) != tt.config {
+			t.Errorf("%s: got broken config %s, want %s", tt.name, body, tt.config)
+		}
+	}
+}
@@ -1,7 +1,46 @@
 package storage
 
+import "fmt"
+
+// DefaultProfile is the name of the profile configs start out with.
+const DefaultProfile = "Default"
+
+// Restart policies of games.
+const (
+	// RestartNever never restarts instances of the game, the default.
+	RestartNever = "never"
+
+	// RestartOnCrash restarts instances of the game that exit with an error.
+	RestartOnCrash = "on_crash"
+
+	// RestartAlways restarts instances of the game whenever they exit,
+	// unless they were stopped from the launcher.
+	RestartAlways = "always"
+)
+
 // Config is the configuration required to run the app.
 type Config struct {
+	// Version is the schema version of the config, older configs are migrated when loaded.
+	Version int `json:"version"`
+
+	Games           []Game    `json:"games"`
+	Gateway         string    `json:"gateway"`
+	DownloadWorkers int       `json:"download_workers"`
+	PatchAddress    string    `json:"patch_address"`
+	LadderAddress   string    `json:"ladder_address"`
+	WineBinary      string    `json:"wine_binary"`
+	WinePrefix      string    `json:"wine_prefix"`
+	Gateways        []Gateway `json:"gateways"`
+
+	// ActiveProfile is the name of the profile Games and Gateway belong to,
+	// the other profiles are kept in Profiles until they're switched to.
+	ActiveProfile string    `json:"active_profile"`
+	Profiles      []Profile `json:"profiles"`
+}
+
+// Profile represents a named setup of games and the gateway they're played on.
+type Profile struct {
+	Name    string `json:"name"`
 	Games   []Game `json:"games"`
 	Gateway string `json:"gateway"`
 }
@@ -15,4 +54,49 @@ type Game struct {
 	OverrideBHCfg bool     `json:"override_bh_cfg"`
 	HD            bool     `json:"hd"`
 	Flags         []string `json:"flags"`
+
+	// AllowUnknownFlags skips validating the flags, for flags the launcher doesn't know about.
+	AllowUnknownFlags bool `json:"allow_unknown_flags"`
+
+	// Profiles set up the instances of the game, by the order they're launched in,
+	// instances without a profile are launched with the flags of the game.
+	Profiles []InstanceProfile `json:"profiles"`
+
+	// LaunchDelay is the least number of milliseconds to wait between
+	// starting instances of the game, 0 uses the default delay.
+	LaunchDelay int `json:"launch_delay"`
+
+	// RestartPolicy is when instances of the game are restarted after exiting,
+	// one of the restart policies, with MaxRestarts attempts in a row.
+	RestartPolicy string `json:"restart_policy"`
+	MaxRestarts   int    `json:"max_restarts"`
+}
+
+// ValidateRestartPolicy will check that the restart policy of the game is known, games without
+// one are never restarted. A misspelled policy would otherwise never restart the game.
+func (g Game) ValidateRestartPolicy() error {
+	switch g.RestartPolicy {
+	case "", RestartNever, RestartOnCrash, RestartAlways:
+		return nil
+	}
+
+	return fmt.Errorf(
+		"unknown restart policy %q for %s, expected %s, %s or %s",
+		g.RestartPolicy, g.Location, RestartNever, RestartOnCrash, RestartAlways,
+	)
+}
+
+// InstanceProfile represents the setup of a single instance of a game.
+type InstanceProfile struct {
+	Label string `json:"label"`
+
+	// Flags replace the flags of the game for the instance, unless nil.
+	Flags []string `json:"flags"`
+}
+
+// Gateway represents a custom gateway added by the user.
+type Gateway struct {
+	Name     string `json:"name"`
+	Host     string `json:"host"`
+	Timezone int    `json:"timezone"`
 }
@@ -0,0 +1,122 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// CurrentVersion is the schema version of the config written by the launcher.
+const CurrentVersion = 2
+
+// migration upgrades the raw config from the version before it. Migrations work on the raw
+// JSON rather than the Config, since the Config always describes the latest version.
+type migration func(raw map[string]interface{}) error
+
+// migrations upgrade the config one version at a time, the first
+// migration upgrades configs from before the config was versioned.
+var migrations = []migration{
+	migrateToVersion1,
+	migrateToVersion2,
+}
+
+// migrate will upgrade the config body to the current version, it returns
+// the migrated body and the version the config had before it.
+func migrate(body []byte) ([]byte, int, error) {
+	var raw map[string]interface{}
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return nil, 0, err
+	}
+
+	// A JSON null decodes without an error, but there's nothing to migrate.
+	if raw == nil {
+		return nil, 0, errors.New("config isn't a JSON object")
+	}
+
+	version, err := configVersion(raw)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if version > CurrentVersion {
+		return nil, version, fmt.Errorf("config version %d is newer than the supported version %d", version, CurrentVersion)
+	}
+
+	if version == CurrentVersion {
+		return body, version, nil
+	}
+
+	for v := version; v < CurrentVersion; v++ {
+		if err := migrations[v](raw); err != nil {
+			return nil, version, fmt.Errorf("migrating config to version %d: %s", v+1, err)
+		}
+
+		raw["version"] = v + 1
+	}
+
+	migrated, err := json.Marshal(raw)
+	if err != nil {
+		return nil, version, err
+	}
+
+	return migrated, version, nil
+}
+
+// configVersion returns the version of the raw config, configs without one are version 0.
+func configVersion(raw map[string]interface{}) (int, error) {
+	v, ok := raw["version"]
+	if !ok || v == nil {
+		return 0, nil
+	}
+
+	// JSON numbers are decoded as floats.
+	version, ok := v.(float64)
+	if !ok || version < 0 || version != float64(int(version)) {
+		return 0, fmt.Errorf("invalid config version %v", v)
+	}
+
+	return int(version), nil
+}
+
+// rawGames returns the games of the raw config, skipping anything that isn't a game.
+func rawGames(raw map[string]interface{}) []map[string]interface{} {
+	list, _ := raw["games"].([]interface{})
+
+	games := make([]map[string]interface{}, 0, len(list))
+	for _, g := range list {
+		if game, ok := g.(map[string]interface{}); ok {
+			games = append(games, game)
+		}
+	}
+
+	return games
+}
+
+// migrateToVersion1 sets the defaults games were added with, on games written before the
+// defaults were set, so they're launched the same way as games added since.
+func migrateToVersion1(raw map[string]interface{}) error {
+	for _, game := range rawGames(raw) {
+		if game["flags"] == nil {
+			game["flags"] = []string{"-w", "-skiptobnet"}
+		}
+
+		if instances, _ := game["instances"].(float64); instances < 1 {
+			game["instances"] = 1
+		}
+	}
+
+	return nil
+}
+
+// migrateToVersion2 makes the games and gateway of the config the default profile.
+func migrateToVersion2(raw map[string]interface{}) error {
+	if name, _ := raw["active_profile"].(string); name == "" {
+		raw["active_profile"] = DefaultProfile
+	}
+
+	if raw["profiles"] == nil {
+		raw["profiles"] = []interface{}{}
+	}
+
+	return nil
+}
@@ -0,0 +1,108 @@
+package storage
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMigrate(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		version int
+		want    *Config
+		err     string
+	}{
+		{
+			name:    "version 0",
+			body:    `{"games":[{"id":"a","location":"/C:/Games/Diablo II"},{"id":"b","flags":["-ns"],"instances":2}],"gateway":"Slashdiablo"}`,
+			version: 0,
+			want: &Config{
+				Version: CurrentVersion,
+				Games: []Game{
+					{ID: "a", Location: "/C:/Games/Diablo II", Instances: 1, Flags: []string{"-w", "-skiptobnet"}},
+					{ID: "b", Instances: 2, Flags: []string{"-ns"}},
+				},
+				Gateway:       "Slashdiablo",
+				ActiveProfile: DefaultProfile,
+				Profiles:      []Profile{},
+			},
+		},
+		{
+			name:    "version 1",
+			body:    `{"version":1,"games":[],"active_profile":"Ladder"}`,
+			version: 1,
+			want: &Config{
+				Version:       CurrentVersion,
+				Games:         []Game{},
+				ActiveProfile: "Ladder",
+				Profiles:      []Profile{},
+			},
+		},
+		{
+			name:    "current version",
+			body:    `{"version":2,"games":[],"active_profile":"Default"}`,
+			version: CurrentVersion,
+			want: &Config{
+				Version:       CurrentVersion,
+				Games:         []Game{},
+				ActiveProfile: DefaultProfile,
+			},
+		},
+		{
+			name: "null",
+			body: `null`,
+			err:  "isn't a JSON object",
+		},
+		{
+			name:    "newer version",
+			body:    `{"version":99}`,
+			version: 99,
+			err:     "newer than the supported version",
+		},
+		{
+			name: "invalid version",
+			body: `{"version":"2"}`,
+			err:  "invalid config version",
+		},
+		{
+			name: "truncated",
+			body: `{"games":[`,
+			err:  "unexpected end of JSON input",
+		},
+	}
+
+	for _, tt := range tests {
+		migrated, version, err := migrate([]byte(tt.body))
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("%s: got error %v, want %q", tt.name, err, tt.err)
+			}
+
+			if version != tt.version {
+				t.Errorf("%s: got version %d, want %d", tt.name, version, tt.version)
+			}
+
+			continue
+		}
+
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %s", tt.name, err)
+		}
+
+		if version != tt.version {
+			t.Errorf("%s: got version %d, want %d", tt.name, version, tt.version)
+		}
+
+		var conf Config
+		if err := json.Unmarshal(migrated, &conf); err != nil {
+			t.Fatalf("%s: unexpected error: %s", tt.name, err)
+		}
+
+		if !reflect.DeepEqual(&conf, tt.want) {
+			t.Errorf("%s: got %+v, want %+v", tt.name, conf, *tt.want)
+		}
+	}
+}
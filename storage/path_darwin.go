@@ -0,0 +1,13 @@
+// +build darwin
+
+package storage
+
+// LocalizePath will localize the stored path for the OS.
+func LocalizePath(path string) string {
+	return path
+}
+
+// StoragePath will convert the path of the OS to the format paths are stored in.
+func StoragePath(path string) string {
+	return path
+}
@@ -0,0 +1,31 @@
+// +build windows
+
+package storage
+
+import (
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+// LocalizePath will localize the stored path for the OS.
+func LocalizePath(path string) string {
+	// Windows uses backslashes for paths, so we'll reverse them.
+	reversed := strings.Replace(path, "/", "\\", -1)
+
+	// Remove the heading backslash.
+	_, i := utf8.DecodeRuneInString(reversed)
+
+	return reversed[i:]
+}
+
+// StoragePath will convert the path of the OS to the format paths are stored in,
+// with forward slashes and a heading slash like the file dialogs give them.
+func StoragePath(path string) string {
+	slashed := filepath.ToSlash(path)
+	if strings.HasPrefix(slashed, "/") {
+		return slashed
+	}
+
+	return "/" + slashed
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"sync"
 )
 
@@ -14,6 +15,9 @@ const (
 
 	// Permissions are the directory permissions for storage.
 	Permissions = 0755
+
+	// filePermissions are the permissions of the files in storage.
+	filePermissions = 0644
 )
 
 // Store represents the data store while hiding implementation behind the interface.
@@ -31,7 +35,7 @@ type store struct {
 
 // Read will return the current configuration.
 func (s *store) Read() (*Config, error) {
-	body, err := ioutil.ReadFile(fmt.Sprintf("%s/%s", s.path, configName))
+	body, err := ioutil.ReadFile(s.configPath())
 	if err != nil {
 		return nil, err
 	}
@@ -51,23 +55,69 @@ func (s *store) Write(config *Config) error {
 	// Unlock it when we're done writing.
 	defer s.writeMutex.Unlock()
 
+	// The config is always written in the current schema.
+	config.Version = CurrentVersion
+
 	// Marshal the data into json.
 	body, err := json.Marshal(config)
 	if err != nil {
 		return err
 	}
 
+	// Keep the config we're replacing, unless it's backed up already, such as when it was edited by hand.
+	if err := s.rotateBackups(); err != nil {
+		return err
+	}
+
 	// Write to the file, replacing the existing config with the new updated one.
-	return ioutil.WriteFile(
-		fmt.Sprintf("%s/%s", s.path, configName),
-		body,
-		Permissions,
-	)
+	if err := writeFile(s.configPath(), body); err != nil {
+		return err
+	}
+
+	// The newest backup is always the last config written.
+	return s.backUp(body)
+}
+
+// writeFile will replace the file with the body in a single rename, after the body has
+// been flushed to disk, so a crash never leaves a partly written file behind.
+func writeFile(path string, body []byte) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+
+	// Clean up the temp file, unless it has been renamed.
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(body); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Chmod(tmp.Name(), filePermissions); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+func (s *store) configPath() string {
+	return filepath.Join(s.path, configName)
 }
 
 // Load will create the directory and config file if it doesn't
 // exist, and will load a default config, if the config exists
-// it will be set on the store.
+// it will be migrated to the current version. A config that can't
+// be read is replaced by the newest backup that can.
 func (s *store) Load() error {
 	// if the config doesn't exist, create it.
 	configExists, err := s.configExists()
@@ -77,18 +127,54 @@ func (s *store) Load() error {
 
 	if !configExists {
 		c := &Config{
-			Games: make([]Game, 0),
+			Games:         make([]Game, 0),
+			ActiveProfile: DefaultProfile,
 		}
 
 		// Write a new config with default settings.
 		return s.Write(c)
 	}
 
-	return nil
+	if err := s.recover(); err != nil {
+		return err
+	}
+
+	return s.migrate()
+}
+
+// migrate will upgrade the config to the current version, the config
+// is backed up before it's migrated.
+func (s *store) migrate() error {
+	body, err := ioutil.ReadFile(s.configPath())
+	if err != nil {
+		return err
+	}
+
+	migrated, version, err := migrate(body)
+	if err != nil {
+		return err
+	}
+
+	if version == CurrentVersion {
+		return nil
+	}
+
+	// Keep the config as it was, in case the migration lost anything.
+	backup := fmt.Sprintf("%s.v%d.bak", s.configPath(), version)
+	if err := writeFile(backup, body); err != nil {
+		return err
+	}
+
+	var conf Config
+	if err := json.Unmarshal(migrated, &conf); err != nil {
+		return err
+	}
+
+	return s.Write(&conf)
 }
 
 func (s *store) configExists() (bool, error) {
-	_, err := os.Stat(fmt.Sprintf("%s/%s", s.path, configName))
+	_, err := os.Stat(s.configPath())
 	if err != nil {
 		if os.IsNotExist(err) {
 			return false, nil